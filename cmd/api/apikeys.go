@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func (app *application) createApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.parseUserIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	if !app.isSelfOrAdmin(r, userID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name        string           `json:"name"`
+		ExpiresAt   *time.Time       `json:"expires_at"`
+		Permissions []types.PermCode `json:"permissions"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Creating a new API key", "user_id", userID, "input", input)
+
+	// A key may only carry permissions held by the credential that creates it. Otherwise, a reduced key could be used
+	// to mint a more powerful one.
+	allowed := app.contextGetPerms(r, true)
+	if app.contextGetAdmin(r, true) {
+		allowed, err = app.models.Permission.GetForUser(r.Context(), userID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve user permissions")
+			return
+		}
+	}
+
+	k := types.GenerateApiKey(userID, input.Name, input.ExpiresAt, input.Permissions...)
+
+	slog.DebugContext(r.Context(), "Validating new API key")
+
+	v := validator.New()
+	k.Validate(v, allowed)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Inserting new API key into database")
+
+	err = app.models.ApiKey.Insert(r.Context(), k)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrDuplicateKey):
+			app.duplicateKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't add API key")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Serializing response")
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"api_key": k},
+		StatusCode: http.StatusCreated,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize API key data")
+	}
+}
+
+func (app *application) readApiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.parseUserIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	if !app.isSelfOrAdmin(r, userID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Fetching API keys for user", "id", userID)
+
+	keys, err := app.models.ApiKey.GetForUser(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve API keys")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"api_keys": keys},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize API key data")
+	}
+}
+
+func (app *application) deleteApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.parseUserIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	if !app.isSelfOrAdmin(r, userID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	id, err := app.parseIdParam(r, "key_id")
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Deleting API key", "user_id", userID, "id", id)
+
+	err = app.models.ApiKey.Delete(r.Context(), userID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't delete API key")
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Deleted API key", "user_id", userID, "id", id)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"message": "API key deleted successfully"},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
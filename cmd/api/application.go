@@ -1,15 +1,31 @@
 package main
 
 import (
+	"context"
 	"sync"
 
+	"github.com/redis/go-redis/v9"
+
 	"github.com/dusktreader/the-hunt/internal/data"
-	"github.com/dusktreader/the-hunt/internal/mailer"
+	"github.com/dusktreader/the-hunt/internal/jwt"
+	"github.com/dusktreader/the-hunt/internal/oidc"
 )
 
 type application struct {
 	config data.Config
 	models data.Models
-	mailer *mailer.Mailer
+	mailer mailSender
+	oidc   *oidc.Provider
+	jwt    *jwt.KeySet
+	redis  *redis.Client
 	waiter *sync.WaitGroup
+
+	mailLimiter data.RateLimiter
+	denylist    *data.Denylist
+	touches     touchLog
+}
+
+// mailSender is satisfied by *mailer.Mailer. Tests provide their own so that nothing is actually sent.
+type mailSender interface {
+	Send(ctx context.Context, args ...any) error
 }
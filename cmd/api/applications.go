@@ -0,0 +1,296 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func (app *application) createApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	var input struct {
+		PostingID int64                   `json:"posting_id"`
+		Status    types.ApplicationStatus `json:"status"`
+		Notes     string                  `json:"notes"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Creating a new application", "input", input)
+
+	v := validator.New()
+
+	a := &types.Application{
+		PostingID: input.PostingID,
+		Status:    input.Status,
+		Notes:     input.Notes,
+	}
+	if a.Status == "" {
+		a.Status = types.StatusInterested
+	}
+
+	slog.DebugContext(r.Context(), "Validating new application")
+
+	a.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Inserting new application into database")
+
+	err = app.models.Application.Insert(r.Context(), tenant, a)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, a.PostingID)
+		case errors.Is(err, types.ErrDuplicateKey):
+			app.duplicateKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't add application")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Serializing response")
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/applications/%d", a.ID))
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"application": a},
+		StatusCode: http.StatusCreated,
+		Headers:    headers,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize application data")
+		return
+	}
+}
+
+func (app *application) readApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+	slog.DebugContext(r.Context(), "Fetching application details", "id", id)
+
+	a, err := app.models.Application.GetOne(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve application")
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Retrieved application", "Application", *a)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"application": a},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize application data")
+	}
+}
+
+func (app *application) readManyApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	slog.DebugContext(r.Context(), "Fetching application list")
+
+	qs := r.URL.Query()
+	v := validator.New()
+	filters := data.ParseFilters(
+		qs,
+		v,
+		data.FilterConstraints{
+			Search: data.ApplicationSearchFields.Check,
+			Sort:   data.ApplicationSortFields.Check,
+		},
+	)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Retrieved filters", "filters", filters)
+
+	applications, metadata, err := app.models.Application.GetMany(r.Context(), tenant, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve applications")
+		return
+	}
+	slog.DebugContext(r.Context(), "Fetched applications", "metadata", metadata)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		StatusCode: http.StatusOK,
+		Envelope: data.Envelope{
+			"applications": applications,
+			"metadata":     metadata,
+		},
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize application data")
+	}
+}
+
+func (app *application) createApplicationTransitionHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Transitioning application", "id", id)
+
+	a, err := app.models.Application.GetOne(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve application")
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Retrieved application", "Application", *a)
+
+	var input struct {
+		Status types.ApplicationStatus `json:"status"`
+		Note   string                  `json:"note"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from := a.Status
+	at := &types.ApplicationTransition{
+		FromStatus: &from,
+		ToStatus:   input.Status,
+		Note:       input.Note,
+	}
+
+	slog.DebugContext(r.Context(), "Validating transition", "id", id, "from", from, "to", at.ToStatus)
+
+	v := validator.New()
+	at.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Recording transition in database")
+
+	err = app.models.Application.Transition(r.Context(), tenant, a, at)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't transition application")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Serializing response")
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope: data.Envelope{
+			"application": a,
+			"transition":  at,
+		},
+		StatusCode: http.StatusCreated,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize application data")
+		return
+	}
+}
+
+func (app *application) readApplicationTransitionsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+	slog.DebugContext(r.Context(), "Fetching application history", "id", id)
+
+	_, err = app.models.Application.GetOne(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve application")
+		}
+		return
+	}
+
+	transitions, err := app.models.Application.GetTransitions(r.Context(), tenant, id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve application history")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"transitions": transitions},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize application history")
+	}
+}
+
+func (app *application) deleteApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+	slog.DebugContext(r.Context(), "Deleting application", "id", id)
+
+	err = app.models.Application.Delete(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't delete application")
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Deleted application", "id", id)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"message": "Application deleted successfully"},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
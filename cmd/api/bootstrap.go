@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+// bootstrapAdmin makes sure that the user configured with ADMIN_EMAIL is an admin. If no user has that email yet, one
+// is created with ADMIN_PASSWORD. An existing user keeps their password and is simply promoted, but only once they have
+// activated their account. Otherwise whoever registered the address first would become an admin without ever proving
+// that they own it.
+func bootstrapAdmin(ctx context.Context, cfg data.Config, models data.Models) error {
+	v := validator.New()
+	cfg.AdminEmail.Validate(v)
+	if !v.Valid() {
+		return fmt.Errorf("ADMIN_EMAIL is invalid: %v", v.Errors())
+	}
+
+	u, err := models.User.GetByEmail(ctx, cfg.AdminEmail)
+	switch {
+	case err == nil:
+		if !u.Activated {
+			return fmt.Errorf("user %s must be activated before they can be promoted to admin", u.Email)
+		}
+		slog.InfoContext(ctx, "Promoting existing user to admin", "id", u.ID, "email", u.Email)
+
+	case errors.Is(err, types.ErrRecordNotFound):
+		slog.InfoContext(ctx, "Creating admin user", "email", cfg.AdminEmail)
+
+		u = &types.User{
+			Name:          "Admin",
+			Email:         cfg.AdminEmail,
+			PlainPassword: cfg.AdminPassword,
+			Activated:     true,
+		}
+
+		u.PlainPassword.Validate(v)
+		if !v.Valid() {
+			return fmt.Errorf("ADMIN_PASSWORD is invalid: %v", v.Errors())
+		}
+
+		u.HashedPassword, err = types.NewHashPW(ctx, u.PlainPassword)
+		if err != nil {
+			return err
+		}
+
+		err = models.User.Insert(ctx, u)
+		if err != nil {
+			return err
+		}
+
+		err = models.Role.AddForUser(ctx, types.SystemUserID, u.ID, cfg.DefaultRoles...)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return err
+	}
+
+	err = models.User.SetAdmin(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Admin user is ready", "id", u.ID, "email", u.Email)
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func TestBootstrapAdmin(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing *types.User
+		wantErr  string
+	}{
+		{name: "new user"},
+		{
+			name:     "activated user",
+			existing: &types.User{Name: "Root", Email: "root@example.com", Activated: true},
+		},
+		{
+			name:     "unactivated user",
+			existing: &types.User{Name: "Root", Email: "root@example.com"},
+			wantErr:  "must be activated before they can be promoted",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			models := data.NewMemoryModels(data.NewMemoryStore())
+			models.Role = noRoles{}
+
+			if c.existing != nil {
+				err := models.User.Insert(ctx, c.existing)
+				if err != nil {
+					t.Fatalf("Couldn't insert user: %v", err)
+				}
+			}
+
+			cfg := data.Config{AdminEmail: "root@example.com", AdminPassword: "correct-horse"}
+			err := bootstrapAdmin(ctx, cfg, models)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("Returned %v; want an error containing %q", err, c.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Returned an error: %v", err)
+			}
+
+			u, err := models.User.GetByEmail(ctx, cfg.AdminEmail)
+			if err != nil {
+				t.Fatalf("Couldn't find the admin user: %v", err)
+			}
+			if u.IsAdmin != (c.wantErr == "") {
+				t.Errorf("IsAdmin = %v; want %v", u.IsAdmin, c.wantErr == "")
+			}
+			if u.Activated != (c.wantErr == "") {
+				t.Errorf("Activated = %v; want %v", u.Activated, c.wantErr == "")
+			}
+		})
+	}
+}
@@ -12,10 +12,13 @@ import (
 )
 
 func (app *application) createCompanyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
 	var input struct {
-		Name      string   `json:"name"`
-		URL       string   `json:"url"`
-		TechStack []string `json:"tech_stack"`
+		Name       string           `json:"name"`
+		URL        string           `json:"url"`
+		TechStack  []string         `json:"tech_stack"`
+		Visibility types.Visibility `json:"visibility"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -24,17 +27,21 @@ func (app *application) createCompanyHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	slog.Debug("Creating a new company", "input", input)
+	slog.DebugContext(r.Context(), "Creating a new company", "input", input)
 
 	v := validator.New()
 
 	c := &types.Company{
-		Name:      input.Name,
-		URL:       input.URL,
-		TechStack: input.TechStack,
+		Name:       input.Name,
+		URL:        input.URL,
+		TechStack:  input.TechStack,
+		Visibility: input.Visibility,
+	}
+	if c.Visibility == "" {
+		c.Visibility = types.VisibilityPrivate
 	}
 
-	slog.Debug("Validating new company")
+	slog.DebugContext(r.Context(), "Validating new company")
 
 	c.Validate(v)
 	if !v.Valid() {
@@ -42,9 +49,9 @@ func (app *application) createCompanyHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	slog.Debug("Inserting new company into database")
+	slog.DebugContext(r.Context(), "Inserting new company into database")
 
-	err = app.models.Company.Insert(c)
+	err = app.models.Company.Insert(r.Context(), tenant, c)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrDuplicateKey):
@@ -55,7 +62,7 @@ func (app *application) createCompanyHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	slog.Debug("Serializing response")
+	slog.DebugContext(r.Context(), "Serializing response")
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/companies/%d", c.ID))
@@ -72,14 +79,16 @@ func (app *application) createCompanyHandler(w http.ResponseWriter, r *http.Requ
 }
 
 func (app *application) readCompanyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
 	id, err := app.parseIdParam(r)
 	if err != nil {
 		app.badIdResponse(w, r, err)
 		return
 	}
-	slog.Debug("Fetching company details", "id", id)
+	slog.DebugContext(r.Context(), "Fetching company details", "id", id)
 
-	c, err := app.models.Company.GetOne(id)
+	c, err := app.models.Company.GetOne(r.Context(), tenant, id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -89,7 +98,7 @@ func (app *application) readCompanyHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
-	slog.Debug("Retrieved company", "Company", *c)
+	slog.DebugContext(r.Context(), "Retrieved company", "Company", *c)
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"company": c},
@@ -101,7 +110,9 @@ func (app *application) readCompanyHandler(w http.ResponseWriter, r *http.Reques
 }
 
 func (app *application) readManyCompaniesHandler(w http.ResponseWriter, r *http.Request) {
-	slog.Debug("Fetching company list")
+	tenant := app.contextGetTenant(r)
+
+	slog.DebugContext(r.Context(), "Fetching company list")
 
 	qs := r.URL.Query()
 	v := validator.New()
@@ -119,13 +130,13 @@ func (app *application) readManyCompaniesHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	slog.Debug("Retrieved filters", "filters", filters)
+	slog.DebugContext(r.Context(), "Retrieved filters", "filters", filters)
 
-	companies, metadata, err := app.models.Company.GetMany(filters)
+	companies, metadata, err := app.models.Company.GetMany(r.Context(), tenant, filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err, "Couldn't retrieve companies")
 	}
-	slog.Debug("Fetched companies", "metadata", metadata)
+	slog.DebugContext(r.Context(), "Fetched companies", "metadata", metadata)
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		StatusCode: http.StatusOK,
@@ -140,15 +151,17 @@ func (app *application) readManyCompaniesHandler(w http.ResponseWriter, r *http.
 }
 
 func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
 	id, err := app.parseIdParam(r)
 	if err != nil {
 		app.badIdResponse(w, r, err)
 		return
 	}
 
-	slog.Debug("Updating company", "id", id)
+	slog.DebugContext(r.Context(), "Updating company", "id", id)
 
-	c, err := app.models.Company.GetOne(id)
+	c, err := app.models.Company.GetOne(r.Context(), tenant, id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -158,12 +171,19 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 		}
 		return
 	}
-	slog.Debug("Retrieved company", "Company", *c)
+	slog.DebugContext(r.Context(), "Retrieved company", "Company", *c)
+
+	if !tenant.Owns(c.OwnerID) {
+		slog.DebugContext(r.Context(), "Company is shared but not owned by tenant", "id", id, "owner_id", c.OwnerID)
+		app.notFoundResponse(w, r, id)
+		return
+	}
 
 	var input struct {
-		Name      string   `json:"name"`
-		URL       string   `json:"url"`
-		TechStack []string `json:"tech_stack"`
+		Name       string           `json:"name"`
+		URL        string           `json:"url"`
+		TechStack  []string         `json:"tech_stack"`
+		Visibility types.Visibility `json:"visibility"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -172,13 +192,16 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	slog.Debug("Updating company with request payload", "id", id, "input", input)
+	slog.DebugContext(r.Context(), "Updating company with request payload", "id", id, "input", input)
 
 	c.Name = input.Name
 	c.URL = input.URL
 	c.TechStack = input.TechStack
+	if input.Visibility != "" {
+		c.Visibility = input.Visibility
+	}
 
-	slog.Debug("Validating updated company", "id", id, "company", c)
+	slog.DebugContext(r.Context(), "Validating updated company", "id", id, "company", c)
 
 	v := validator.New()
 	c.Validate(v)
@@ -187,9 +210,9 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	slog.Debug("Updating company in database")
+	slog.DebugContext(r.Context(), "Updating company in database")
 
-	err = app.models.Company.Update(c)
+	err = app.models.Company.Update(r.Context(), tenant, c)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrEditConflict):
@@ -202,7 +225,7 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	slog.Debug("Serializing response")
+	slog.DebugContext(r.Context(), "Serializing response")
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"company": c},
@@ -215,15 +238,17 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 }
 
 func (app *application) updatePartialCompanyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
 	id, err := app.parseIdParam(r)
 	if err != nil {
 		app.badIdResponse(w, r, err)
 		return
 	}
 
-	slog.Debug("Partially updating company", "id", id)
+	slog.DebugContext(r.Context(), "Partially updating company", "id", id)
 
-	version, err := app.models.Company.GetVersion(id)
+	version, err := app.models.Company.GetVersion(r.Context(), tenant, id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -233,7 +258,7 @@ func (app *application) updatePartialCompanyHandler(w http.ResponseWriter, r *ht
 		}
 		return
 	}
-	slog.Debug("Retrieved version", "Version", version)
+	slog.DebugContext(r.Context(), "Retrieved version", "Version", version)
 
 	pc := types.PartialCompany{}
 
@@ -242,9 +267,9 @@ func (app *application) updatePartialCompanyHandler(w http.ResponseWriter, r *ht
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	slog.Debug("Updating company with request payload", "id", id, "input", pc)
+	slog.DebugContext(r.Context(), "Updating company with request payload", "id", id, "input", pc)
 
-	slog.Debug("Validating partial company", "id", id, "partial_company", pc)
+	slog.DebugContext(r.Context(), "Validating partial company", "id", id, "partial_company", pc)
 	v := validator.New()
 	pc.Validate(v)
 	if !v.Valid() {
@@ -252,19 +277,21 @@ func (app *application) updatePartialCompanyHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	slog.Debug("Updating company in database")
-	c, err := app.models.Company.PartialUpdate(id, version, &pc)
+	slog.DebugContext(r.Context(), "Updating company in database")
+	c, err := app.models.Company.PartialUpdate(r.Context(), tenant, id, version, &pc)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, types.ErrDuplicateKey):
+			app.duplicateKeyResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err, "Couldn't update company")
 		}
 		return
 	}
 
-	slog.Debug("Serializing response")
+	slog.DebugContext(r.Context(), "Serializing response")
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"company": c},
@@ -277,14 +304,16 @@ func (app *application) updatePartialCompanyHandler(w http.ResponseWriter, r *ht
 }
 
 func (app *application) deleteCompanyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
 	id, err := app.parseIdParam(r)
 	if err != nil {
 		app.badIdResponse(w, r, err)
 		return
 	}
-	slog.Debug("Deleting company", "id", id)
+	slog.DebugContext(r.Context(), "Deleting company", "id", id)
 
-	err = app.models.Company.Delete(id)
+	err = app.models.Company.Delete(r.Context(), tenant, id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -294,7 +323,7 @@ func (app *application) deleteCompanyHandler(w http.ResponseWriter, r *http.Requ
 		}
 		return
 	}
-	slog.Debug("Deleted company", "id", id)
+	slog.DebugContext(r.Context(), "Deleted company", "id", id)
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"message": "Company deleted successfully"},
@@ -13,6 +13,9 @@ type contextKey string
 const userContextKey = contextKey("user")
 const permsContextKey = contextKey("perms")
 const adminContextKey = contextKey("admin")
+const tokenContextKey = contextKey("token")
+const apiKeyContextKey = contextKey("api-key")
+const routeContextKey = contextKey("route")
 
 func (app *application) contextSetUser(r *http.Request, user *types.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -23,7 +26,7 @@ func (app *application) contextGetUser(r *http.Request, dontPanic ...bool) *type
 	user, ok := r.Context().Value(userContextKey).(*types.User)
 	if !ok {
 		if len(dontPanic) > 0 && dontPanic[0] {
-			slog.Debug("User not found in context, but not panicking")
+			slog.DebugContext(r.Context(), "User not found in context, but not panicking")
 			return nil
 		} else {
 			panic("could not find user in request context")
@@ -41,7 +44,7 @@ func (app *application) contextGetPerms(r *http.Request, dontPanic ...bool) *typ
 	perms, ok := r.Context().Value(permsContextKey).(*types.PermissionSet)
 	if !ok {
 		if len(dontPanic) > 0 && dontPanic[0] {
-			slog.Debug("Permissions not found in context, but not panicking")
+			slog.DebugContext(r.Context(), "Permissions not found in context, but not panicking")
 			return nil
 		} else {
 			panic("could not find permissions in request context")
@@ -50,6 +53,47 @@ func (app *application) contextGetPerms(r *http.Request, dontPanic ...bool) *typ
 	return perms
 }
 
+func (app *application) contextSetToken(r *http.Request, token *types.Token) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey, token)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetToken(r *http.Request, dontPanic ...bool) *types.Token {
+	token, ok := r.Context().Value(tokenContextKey).(*types.Token)
+	if !ok {
+		if len(dontPanic) > 0 && dontPanic[0] {
+			slog.DebugContext(r.Context(), "Token not found in context, but not panicking")
+			return nil
+		} else {
+			panic("could not find token in request context")
+		}
+	}
+	return token
+}
+
+func (app *application) contextSetApiKey(r *http.Request, key *types.ApiKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// contextGetApiKey returns nil if the request wasn't authenticated with an API key.
+func (app *application) contextGetApiKey(r *http.Request) *types.ApiKey {
+	key, ok := r.Context().Value(apiKeyContextKey).(*types.ApiKey)
+	if !ok {
+		return nil
+	}
+	return key
+}
+
+// contextGetActorID returns the ID of the user making the request or types.SystemUserID if there isn't one.
+func (app *application) contextGetActorID(r *http.Request) int64 {
+	user := app.contextGetUser(r, true)
+	if user == nil || user.IsAnonymous() {
+		return types.SystemUserID
+	}
+	return user.ID
+}
+
 func (app *application) contextSetAdmin(r *http.Request) *http.Request {
 	ctx := context.WithValue(r.Context(), adminContextKey, true)
 	return r.WithContext(ctx)
@@ -59,7 +103,7 @@ func (app *application) contextGetAdmin(r *http.Request, dontPanic ...bool) bool
 	isAdmin, ok := r.Context().Value(adminContextKey).(bool)
 	if !ok {
 		if len(dontPanic) > 0 && dontPanic[0] {
-			slog.Debug("Admin not found in context, but not panicking")
+			slog.DebugContext(r.Context(), "Admin not found in context, but not panicking")
 			return false
 		} else {
 			panic("could not find admin in request context")
@@ -67,3 +111,34 @@ func (app *application) contextGetAdmin(r *http.Request, dontPanic ...bool) bool
 	}
 	return isAdmin
 }
+
+// contextGetTenant derives whose hunt data the request may touch. Admins are explicitly granted access across all
+// tenants. Everyone else is limited to their own records.
+func (app *application) contextGetTenant(r *http.Request) types.Tenant {
+	user := app.contextGetUser(r)
+	if app.contextGetAdmin(r, true) {
+		return types.AdminTenant(user.ID)
+	}
+	return types.UserTenant(user.ID)
+}
+
+// contextSetRoute stores a place for the matched route template. Routing happens after the access log and metrics
+// middleware, so the template is written into it later by recordRoute. If the request already has a place, it is
+// reused so that every middleware sees the same template.
+func (app *application) contextSetRoute(r *http.Request) (*http.Request, *string) {
+	if route := app.contextGetRoute(r); route != nil {
+		return r, route
+	}
+	route := new(string)
+	ctx := context.WithValue(r.Context(), routeContextKey, route)
+	return r.WithContext(ctx), route
+}
+
+// contextGetRoute returns nil if the request didn't pass through the access log or metrics middleware.
+func (app *application) contextGetRoute(r *http.Request) *string {
+	route, ok := r.Context().Value(routeContextKey).(*string)
+	if !ok {
+		return nil
+	}
+	return route
+}
@@ -3,26 +3,24 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"time"
 
-	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
-)
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
 
-func buildDSN(cfg data.Config) string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.DBUser,
-		cfg.DBPswd,
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBName,
-	)
-}
+	"github.com/dusktreader/the-hunt/internal/data"
+)
 
+// openDB connects through a driver wrapper that traces every query as a child of the span in the query's context. The
+// SQL is recorded on the span, which is how the statements that the models assemble show up in a trace.
 func openDB(dsn string, cfg data.Config) (*sql.DB, error) {
-	db, err := sql.Open("postgres", dsn)
+	db, err := otelsql.Open(
+		"postgres",
+		dsn,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
 		return nil, err
 	}
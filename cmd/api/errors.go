@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/logs"
 	"github.com/dusktreader/the-hunt/internal/types"
 )
 
@@ -15,18 +21,24 @@ func (app *application) errorResponse(
 	r *http.Request,
 	ep *data.ErrorPackage,
 ) {
+	ep.RequestID = logs.RequestID(r.Context())
 	app.logError(r, ep)
 	err := app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"error": ep},
 		StatusCode: ep.StatusCode,
 	})
 	if err != nil {
-		slog.Error("Couldn't serialize error response", "error", err)
+		slog.ErrorContext(r.Context(), "Couldn't serialize error response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error, message ...string) {
+	if errors.Is(types.MapTimeout(err), types.ErrTimeout) {
+		app.timeoutResponse(w, r, err)
+		return
+	}
+
 	msg := "There was an error processing your request"
 	if len(message) > 0 {
 		msg = message[0]
@@ -38,6 +50,22 @@ func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// timeoutResponse is a 503 because running out of time usually means that the database is overloaded, so the request
+// is worth retrying shortly. If the client gave up first, there's nobody left to tell.
+func (app *application) timeoutResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		slog.InfoContext(r.Context(), "Client closed the request before it finished", "error", err)
+		return
+	}
+
+	w.Header().Set("Retry-After", "1")
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusServiceUnavailable,
+		Message:    "The request took too long to process. Please try again",
+		Error:      err,
+	})
+}
+
 func (app *application) badIdResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.errorResponse(w, r, &data.ErrorPackage{
 		StatusCode: http.StatusBadRequest,
@@ -139,6 +167,13 @@ func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request
 	})
 }
 
+func (app *application) sessionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusForbidden,
+		Message:    "Forbidden. Please log in to manage your account; API keys can't be used here",
+	})
+}
+
 func (app *application) invalidTokenResponse(w http.ResponseWriter, r *http.Request, scope types.TokenScope) {
 	if scope == types.ScopeAuthentication {
 		w.Header().Set("WWW-Authenticate", "Bearer")
@@ -148,3 +183,55 @@ func (app *application) invalidTokenResponse(w http.ResponseWriter, r *http.Requ
 		Message:    fmt.Sprintf("Invalid token; please request new %s token", scope),
 	})
 }
+
+func (app *application) invalidApiKeyResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "ApiKey")
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusUnauthorized,
+		Message:    "Invalid or expired API key",
+	})
+}
+
+func (app *application) invalidMFACodeResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusUnauthorized,
+		Message:    "Invalid MFA code",
+	})
+}
+
+func (app *application) mfaAlreadyEnabledResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusConflict,
+		Message:    "MFA is already enabled for this user",
+	})
+}
+
+func (app *application) accountLockedResponse(w http.ResponseWriter, r *http.Request, until time.Time) {
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusLocked,
+		Message:    "Too many failed login attempts. Please try again later",
+	})
+}
+
+func (app *application) oidcNotConfiguredResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusNotFound,
+		Message:    "OIDC login is not configured",
+	})
+}
+
+func (app *application) oidcLoginFailedResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusUnauthorized,
+		Message:    "External login failed. Please try logging in again",
+	})
+}
+
+func (app *application) emailNotVerifiedResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, &data.ErrorPackage{
+		StatusCode: http.StatusForbidden,
+		Message:    "The identity provider has not verified your email address",
+	})
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,10 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -37,23 +40,40 @@ func Close(args ...any) {
 	os.Exit(0)
 }
 
-func (app *application) background(fn func() error) {
+// background runs fn in a goroutine that the server waits for before it shuts down. The task gets a copy of ctx that
+// keeps its values, like the active span, but isn't canceled when the request finishes.
+func (app *application) background(ctx context.Context, fn func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
 	app.waiter.Add(1)
 	go func() {
 		defer app.waiter.Done()
 		defer func() {
 			if err := recover(); err != nil {
-				slog.Error("Recovered from panic", "error", err)
+				slog.ErrorContext(ctx, "Recovered from panic", "error", err)
 			}
 		}()
 
-		err := fn()
+		err := fn(ctx)
 		if err != nil {
-			slog.Error("Background task failed", "error", err)
+			slog.ErrorContext(ctx, "Background task failed", "error", err)
 		}
 	}()
 }
 
+// allowMail reports whether another email may be sent to the address right now. This keeps the unauthenticated
+// endpoints that send mail from being used to flood somebody's inbox.
+func (app *application) allowMail(ctx context.Context, email types.Email) bool {
+	if !app.config.LimitEnabled {
+		return true
+	}
+	res, err := app.mailLimiter.Allow(ctx, strings.ToLower(string(email)))
+	if err != nil {
+		slog.ErrorContext(ctx, "Couldn't check mail rate limit. Allowing mail", "error", err)
+		return true
+	}
+	return res.Allowed
+}
+
 func (app *application) logError(r *http.Request, er *data.ErrorPackage) {
 	var logMessage string
 	if er.LogMessage == "" {
@@ -61,18 +81,42 @@ func (app *application) logError(r *http.Request, er *data.ErrorPackage) {
 	} else {
 		logMessage = er.LogMessage
 	}
-	slog.Error(
+	slog.ErrorContext(
+		r.Context(),
 		logMessage,
 		"error", er.Error,
 		"method", r.Method,
-		"uri", r.URL.RequestURI(),
+		"path", r.URL.Path,
 	)
 
 }
 
-func (app *application) parseIdParam(r *http.Request) (int64, error) {
+// staticParam sends requests whose wildcard segment matches one of the static values to a dedicated handler and
+// everything else to the fallback. httprouter won't register a static segment in the same position as a wildcard, so
+// routes like PUT /v1/users/password have to share the PUT /v1/users/:id registration.
+func (app *application) staticParam(
+	name string,
+	statics map[string]http.HandlerFunc,
+	fallback http.HandlerFunc,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+		if handler, ok := statics[params.ByName(name)]; ok {
+			handler(w, r)
+			return
+		}
+		fallback(w, r)
+	}
+}
+
+func (app *application) parseIdParam(r *http.Request, name ...string) (int64, error) {
+	paramName := "id"
+	if len(name) > 0 {
+		paramName = name[0]
+	}
+
 	params := httprouter.ParamsFromContext(r.Context())
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(paramName), 10, 64)
 	if err != nil {
 		return 0, fmt.Errorf("int is required")
 	} else if id < 0 {
@@ -83,6 +127,30 @@ func (app *application) parseIdParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// parseUserIdParam reads a user ID from the :id parameter. The special value "me" resolves to the user that made the
+// request.
+func (app *application) parseUserIdParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	if params.ByName("id") != "me" {
+		return app.parseIdParam(r)
+	}
+
+	user := app.contextGetUser(r, true)
+	if user == nil || user.IsAnonymous() {
+		return 0, fmt.Errorf("me requires an authenticated user")
+	}
+	return user.ID, nil
+}
+
+// isSelfOrAdmin reports whether the request was made by the given user or by an admin.
+func (app *application) isSelfOrAdmin(r *http.Request, userID int64) bool {
+	if app.contextGetAdmin(r, true) {
+		return true
+	}
+	user := app.contextGetUser(r, true)
+	return user != nil && !user.IsAnonymous() && user.ID == userID
+}
+
 func (app *application) writeJSON(w http.ResponseWriter, jr *data.JSONResponse) error {
 	var serialized []byte
 	var err error
@@ -143,7 +211,8 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 			default:
 				mappedErr = err
 		}
-		slog.Debug(
+		slog.DebugContext(
+			r.Context(),
 			"There was an error reading JSON from the request",
 			"original_error",
 			err,
@@ -152,11 +221,11 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 		)
 		return mappedErr
 	}
-	slog.Debug("Decoded JSON payload", "payload", dst)
+	slog.DebugContext(r.Context(), "Decoded JSON payload", "payload", dst)
 
 	err = dec.Decode(&struct{}{})
 	if !errors.Is(err, io.EOF) {
-		slog.Debug("The body contained multiple JSON values")
+		slog.DebugContext(r.Context(), "The body contained multiple JSON values")
 		return fmt.Errorf("body must only contain a single JSON value")
 	}
 	return nil
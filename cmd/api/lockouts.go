@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tomasen/realip"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func lockoutSubject(email types.Email) string {
+	return strings.ToLower(string(email))
+}
+
+// checkLockout responds with 423 Locked and returns false if logins from the email or the client's IP address are
+// currently blocked.
+func (app *application) checkLockout(w http.ResponseWriter, r *http.Request, email types.Email) bool {
+	if !app.config.LockoutEnabled {
+		return true
+	}
+
+	until, err := app.models.LoginFailure.LockedUntil(r.Context(), lockoutSubject(email), realip.FromRequest(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't check for lockout")
+		return false
+	}
+
+	if until.After(time.Now()) {
+		slog.DebugContext(r.Context(), "Login is locked out", "email", email, "until", until)
+		app.accountLockedResponse(w, r, until)
+		return false
+	}
+
+	return true
+}
+
+// recordLoginFailure counts a failed login against both the email and the client's IP address. When the email first
+// becomes locked, its owner is notified in case somebody else is guessing their password.
+func (app *application) recordLoginFailure(r *http.Request, email types.Email) error {
+	if !app.config.LockoutEnabled {
+		return nil
+	}
+
+	emailPolicy := types.LockoutPolicy{
+		MaxFailures: app.config.LockoutEmailFailures,
+		BaseDelay:   app.config.LockoutBaseDelay,
+		MaxDelay:    app.config.LockoutMaxDelay,
+		Window:      app.config.LockoutWindow,
+	}
+	ipPolicy := types.LockoutPolicy{
+		MaxFailures: app.config.LockoutIPFailures,
+		BaseDelay:   app.config.LockoutBaseDelay,
+		MaxDelay:    app.config.LockoutMaxDelay,
+		Window:      app.config.LockoutWindow,
+	}
+
+	_, err := app.models.LoginFailure.RecordFailure(r.Context(), types.FailureByIP, realip.FromRequest(r), ipPolicy)
+	if err != nil {
+		return err
+	}
+
+	failures, err := app.models.LoginFailure.RecordFailure(
+		r.Context(),
+		types.FailureByEmail,
+		lockoutSubject(email),
+		emailPolicy,
+	)
+	if err != nil {
+		return err
+	}
+
+	if failures != emailPolicy.MaxFailures {
+		return nil
+	}
+
+	u, err := app.models.User.GetByEmail(r.Context(), email)
+	if err != nil {
+		if errors.Is(err, types.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	slog.InfoContext(r.Context(), "Account locked after repeated login failures", "id", u.ID)
+
+	templateData := map[string]any{
+		"user":  u,
+		"until": time.Now().Add(emailPolicy.Delay(failures)).Format(time.RFC1123),
+	}
+	send := func(ctx context.Context) error {
+		return app.mailer.Send(ctx, u.Email, "account_locked.tmpl", templateData)
+	}
+	app.background(r.Context(), send)
+
+	return nil
+}
+
+// resetLoginFailures clears the email's failure count after a successful login. The IP address keeps its count, or
+// anybody with an account of their own could wipe it between guesses at somebody else's password.
+func (app *application) resetLoginFailures(r *http.Request, email types.Email) error {
+	if !app.config.LockoutEnabled {
+		return nil
+	}
+
+	return app.models.LoginFailure.Reset(r.Context(), types.FailureByEmail, lockoutSubject(email))
+}
+
+func (app *application) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Unlocking user", "id", id)
+
+	u, err := app.models.User.GetOne(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve user")
+		}
+		return
+	}
+
+	err = app.models.LoginFailure.Reset(r.Context(), types.FailureByEmail, lockoutSubject(u.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't unlock user")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"message": "User unlocked successfully"},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
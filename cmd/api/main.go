@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"expvar"
 	"flag"
 	"fmt"
@@ -11,14 +12,22 @@ import (
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
 
 	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/jwt"
 	"github.com/dusktreader/the-hunt/internal/logs"
 	"github.com/dusktreader/the-hunt/internal/mailer"
+	"github.com/dusktreader/the-hunt/internal/metrics"
+	"github.com/dusktreader/the-hunt/internal/oidc"
+	"github.com/dusktreader/the-hunt/internal/tracing"
+	"github.com/dusktreader/the-hunt/internal/types"
 )
 
 func main() {
 	showVer := flag.Bool("version", false, "Display version and exit")
+	migrateOnStart := flag.Bool("migrate-on-start", false, "Apply pending migrations before starting")
 	flag.Parse()
 	if *showVer {
 		fmt.Println(Version())
@@ -30,19 +39,48 @@ func main() {
 	var cfg data.Config
 	err := env.Parse(&cfg)
 	MaybeDie(err)
+	err = cfg.Validate()
+	MaybeDie(err)
 
 	logs.InitLogger(cfg)
 
-	dsn := buildDSN(cfg)
+	shutdownTracing, err := tracing.Init(context.Background(), cfg, Version())
+	MaybeDie(err)
+
+	dsn := cfg.DSN()
 	slog.Info("Attempting to connect to the database", "dsn", dsn)
 	db, err := openDB(dsn, cfg)
 	MaybeDie(err)
 	defer db.Close()
 	slog.Info("Database connection pool established")
+	metrics.RegisterDB(db, cfg.DBName)
+
+	if flag.Arg(0) == "migrate" {
+		MaybeDie(migrate(context.Background(), db, flag.Arg(1)))
+		Close("Migrations finished")
+	}
+
+	if *migrateOnStart {
+		MaybeDie(migrate(context.Background(), db, "up"))
+	}
+
+	models := data.NewModels(db, data.NewModelConfig(cfg))
+
+	if flag.Arg(0) == "bootstrap-admin" {
+		MaybeDie(bootstrapAdmin(context.Background(), cfg, models))
+		Close("Admin bootstrapped")
+	}
 
 	mailer, err := mailer.New(cfg)
 	MaybeDie(err)
 
+	var provider *oidc.Provider
+	if cfg.OIDCIssuerURL != "" {
+		slog.Info("Discovering OIDC provider", "issuer", cfg.OIDCIssuerURL)
+		provider, err = oidc.New(context.Background(), cfg)
+		MaybeDie(err)
+	}
+
 	if cfg.APIEnv.IsDev() {
 		expvar.NewString("version").Set(Version())
 		expvar.Publish("goroutines", expvar.Func(func() any {
@@ -59,13 +97,52 @@ func main() {
 		}))
 	}
 
+	var keys *jwt.KeySet
+	var denylist *data.Denylist
+	switch cfg.TokenMode {
+	case types.TokenModeOpaque:
+	case types.TokenModeJWT:
+		slog.Info("Issuing signed JWT access tokens", "algorithm", cfg.JWTAlgorithm)
+		keys, err = jwt.New(cfg)
+		MaybeDie(err)
+		denylist = data.NewDenylist(db, cfg)
+		MaybeDie(denylist.Refresh(context.Background()))
+		go denylist.RefreshCycle()
+	default:
+		MaybeDie(fmt.Errorf("unknown token mode: %s", cfg.TokenMode))
+	}
+
+	var rdb *redis.Client
+	switch cfg.LimitStore {
+	case data.LimitStoreMemory:
+	case data.LimitStoreRedis:
+		slog.Info("Connecting to Redis for rate limiting")
+		rdb, err = openRedis(cfg)
+		MaybeDie(err)
+		defer rdb.Close()
+	default:
+		MaybeDie(fmt.Errorf("unknown rate limit store: %s", cfg.LimitStore))
+	}
+
+	mailLimiter := data.NewRateLimiter(cfg, rdb, data.LimitPolicy{
+		Name:  "mail",
+		Limit: rate.Every(cfg.MailLimitInterval),
+		Burst: cfg.MailLimitBurst,
+	})
+
 	app := &application{
-		config: cfg,
-		models: data.NewModels(db, data.NewModelConfig(cfg)),
-		mailer: mailer,
-		waiter: new(sync.WaitGroup),
+		config:      cfg,
+		models:      models,
+		mailer:      mailer,
+		oidc:        provider,
+		jwt:         keys,
+		redis:       rdb,
+		waiter:      new(sync.WaitGroup),
+		mailLimiter: mailLimiter,
+		denylist:    denylist,
 	}
 
 	MaybeDie(app.serve())
+	MaybeDie(shutdownTracing(context.Background()))
 	Close("App finished")
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+// parseMFATarget reads the user ID from the route. MFA can only be managed by the user it protects, so anybody else
+// gets a forbidden response. If anything is wrong, the error response is written and nil is returned.
+func (app *application) parseMFATarget(w http.ResponseWriter, r *http.Request) *types.User {
+	userID, err := app.parseUserIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return nil
+	}
+
+	user := app.contextGetUser(r)
+	if user.ID != userID {
+		app.forbiddenResponse(w, r)
+		return nil
+	}
+
+	return user
+}
+
+// checkMFACode accepts either a TOTP code or one of the user's recovery codes. If the code is accepted, it can't be
+// used again.
+func (app *application) checkMFACode(ctx context.Context, mfa *types.MFA, code types.MFACode) error {
+	if code.IsTOTP() {
+		step, ok := types.VerifyTOTP(mfa.Secret, code, time.Now())
+		if !ok {
+			return types.ErrInvalidMFACode
+		}
+		return app.models.MFA.UseStep(ctx, mfa.UserID, step)
+	}
+
+	if !mfa.Enabled {
+		return types.ErrInvalidMFACode
+	}
+	return app.models.MFA.UseRecoveryCode(ctx, mfa.UserID, code)
+}
+
+func (app *application) enrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.parseMFATarget(w, r)
+	if user == nil {
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Enrolling user in MFA", "id", user.ID)
+
+	secret := types.NewTOTPSecret()
+	codes := types.GenerateRecoveryCodes()
+
+	err := app.models.MFA.Enroll(r.Context(), user.ID, secret, codes)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrDuplicateKey):
+			app.mfaAlreadyEnabledResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't enroll user in MFA")
+		}
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope: data.Envelope{
+			"mfa": data.Envelope{
+				"secret":         secret,
+				"otpauth_uri":    types.TOTPURI(app.config.MFAIssuer, string(user.Email), secret),
+				"recovery_codes": codes,
+			},
+		},
+		StatusCode: http.StatusCreated,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize MFA data")
+	}
+}
+
+func (app *application) verifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.parseMFATarget(w, r)
+	if user == nil {
+		return
+	}
+
+	var input struct {
+		Code types.MFACode `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	input.Code.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Verifying MFA enrollment", "id", user.ID)
+
+	mfa, err := app.models.MFA.GetForUser(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, user.ID)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve MFA settings")
+		}
+		return
+	}
+
+	err = app.checkMFACode(r.Context(), mfa, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrInvalidMFACode):
+			app.invalidMFACodeResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't verify MFA code")
+		}
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"message": "MFA is enabled"},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
+
+// startMFAChallenge responds to a correct password with a short-lived token instead of a session. The token can only
+// be exchanged for a session through loginMFAHandler.
+func (app *application) startMFAChallenge(w http.ResponseWriter, r *http.Request, userID int64) {
+	slog.DebugContext(r.Context(), "User has MFA enabled. Issuing pending token", "id", userID)
+
+	t, err := app.models.Token.New(r.Context(), userID, app.config.MFAPendingTTL, types.ScopeMFAPending)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't authenticate user")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"mfa_pending": t},
+		StatusCode: http.StatusAccepted,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize data")
+	}
+}
+
+func (app *application) loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token types.PlainToken `json:"mfa_token"`
+		Code  types.MFACode    `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	input.Token.Validate(v)
+	input.Code.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Completing MFA login")
+
+	t, err := app.models.Token.GetOne(r.Context(), input.Token, types.ScopeMFAPending)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r, types.ScopeMFAPending)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve MFA token")
+		}
+		return
+	}
+
+	u, err := app.models.User.GetOne(r.Context(), t.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve user")
+		return
+	}
+
+	// Codes are guessed far more easily than passwords, so failures here count toward the same lockout.
+	if !app.checkLockout(w, r, u.Email) {
+		return
+	}
+
+	mfa, err := app.models.MFA.GetForUser(r.Context(), t.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve MFA settings")
+		return
+	}
+
+	err = app.checkMFACode(r.Context(), mfa, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrInvalidMFACode):
+			err = app.recordLoginFailure(r, u.Email)
+			if err != nil {
+				app.serverErrorResponse(w, r, err, "Couldn't record login failure")
+				return
+			}
+			app.invalidMFACodeResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't verify MFA code")
+		}
+		return
+	}
+
+	err = app.resetLoginFailures(r, u.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't reset login failures")
+		return
+	}
+
+	err = app.models.Token.DeleteForUser(r.Context(), string(types.ScopeMFAPending), t.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't revoke MFA token")
+		return
+	}
+
+	app.startSession(w, r, t.UserID)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// oneMFA stands in for the MFA store. A single user is enrolled, and steps and recovery codes are used up the same way
+// that the Postgres store uses them up.
+type oneMFA struct {
+	data.MFAStore
+	mfa   *types.MFA
+	codes map[types.MFACode]bool
+}
+
+func (s *oneMFA) GetForUser(_ context.Context, userID int64) (*types.MFA, error) {
+	if userID != s.mfa.UserID {
+		return nil, types.ErrRecordNotFound
+	}
+	mfa := *s.mfa
+	return &mfa, nil
+}
+
+func (s *oneMFA) UseStep(_ context.Context, userID int64, step int64) error {
+	if userID != s.mfa.UserID || step <= s.mfa.LastStep {
+		return types.ErrInvalidMFACode
+	}
+	s.mfa.LastStep = step
+	return nil
+}
+
+func (s *oneMFA) UseRecoveryCode(_ context.Context, userID int64, code types.MFACode) error {
+	if userID != s.mfa.UserID || !s.codes[code.Normalize()] {
+		return types.ErrInvalidMFACode
+	}
+	delete(s.codes, code.Normalize())
+	return nil
+}
+
+// countedFailures stands in for the login failure store. Subjects are locked for an hour once they reach the policy's
+// limit, and the window is ignored.
+type countedFailures struct {
+	data.LoginFailureStore
+	counts map[string]int
+	locked map[string]bool
+}
+
+func (s countedFailures) LockedUntil(_ context.Context, email string, ip string) (time.Time, error) {
+	if s.locked[email] || s.locked[ip] {
+		return time.Now().Add(time.Hour), nil
+	}
+	return time.Time{}, nil
+}
+
+func (s countedFailures) RecordFailure(
+	_ context.Context,
+	_ types.LoginFailureKind,
+	subject string,
+	policy types.LockoutPolicy,
+) (int, error) {
+	s.counts[subject]++
+	if s.counts[subject] >= policy.MaxFailures {
+		s.locked[subject] = true
+	}
+	return s.counts[subject], nil
+}
+
+func (s countedFailures) Reset(_ context.Context, _ types.LoginFailureKind, subject string) error {
+	delete(s.counts, subject)
+	delete(s.locked, subject)
+	return nil
+}
+
+func TestLoginMFA(t *testing.T) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+
+	app, _, s := newTestApp(t)
+	app.config.LockoutEnabled = true
+	app.config.LockoutEmailFailures = 3
+	app.config.LockoutIPFailures = 100
+
+	secret := types.NewTOTPSecret()
+	recovery := types.GenerateRecoveryCodes()
+	mfa := &oneMFA{
+		mfa:   &types.MFA{UserID: s.member.ID, Secret: secret, Enabled: true},
+		codes: make(map[types.MFACode]bool),
+	}
+	for _, c := range recovery {
+		mfa.codes[c.Normalize()] = true
+	}
+	app.models.MFA = mfa
+	failures := countedFailures{counts: make(map[string]int), locked: make(map[string]bool)}
+	app.models.LoginFailure = failures
+
+	step := types.TOTPStep(time.Now())
+	current, err := types.TOTPCode(secret, step)
+	if err != nil {
+		t.Fatalf("Couldn't compute TOTP code: %v", err)
+	}
+	stale, err := types.TOTPCode(secret, step-10)
+	if err != nil {
+		t.Fatalf("Couldn't compute TOTP code: %v", err)
+	}
+
+	var pending string
+	login := func(t *testing.T) int {
+		body := fmt.Sprintf(`{"email": %q, "password": "pa55word"}`, s.member.Email)
+		w := httptest.NewRecorder()
+		app.loginHandler(w, httptest.NewRequest("POST", "/v1/login", strings.NewReader(body)))
+
+		var resp struct {
+			Pending *types.Token `json:"mfa_pending"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatalf("Couldn't decode response: %v", err)
+		}
+		if resp.Pending != nil {
+			pending = string(resp.Pending.Plaintext)
+		}
+		return w.Code
+	}
+	verify := func(code string) func(t *testing.T) int {
+		return func(t *testing.T) int {
+			body := fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, pending, code)
+			w := httptest.NewRecorder()
+			app.loginMFAHandler(w, httptest.NewRequest("POST", "/v1/login/mfa", strings.NewReader(body)))
+			return w.Code
+		}
+	}
+
+	// The steps run in order, so later steps see the codes used up and the failures counted by earlier ones.
+	steps := []struct {
+		name string
+		do   func(t *testing.T) int
+		want int
+	}{
+		{name: "password asks for a code", do: login, want: http.StatusAccepted},
+		{name: "wrong code", do: verify(stale), want: http.StatusUnauthorized},
+		{name: "right code starts a session", do: verify(current), want: http.StatusCreated},
+		{name: "pending token is used up", do: verify(current), want: http.StatusUnauthorized},
+
+		{name: "password again", do: login, want: http.StatusAccepted},
+		{name: "replayed code", do: verify(current), want: http.StatusUnauthorized},
+		{name: "recovery code starts a session", do: verify(string(recovery[0])), want: http.StatusCreated},
+
+		{name: "password once more", do: login, want: http.StatusAccepted},
+		{name: "reused recovery code", do: verify(string(recovery[0])), want: http.StatusUnauthorized},
+		{name: "second failure in a row", do: verify(stale), want: http.StatusUnauthorized},
+		{name: "password doesn't clear the failures", do: login, want: http.StatusAccepted},
+		{name: "third failure in a row", do: verify(stale), want: http.StatusUnauthorized},
+		{name: "locked out of the code", do: verify(string(recovery[1])), want: http.StatusLocked},
+		{name: "locked out of the password", do: login, want: http.StatusLocked},
+	}
+
+	for _, step := range steps {
+		got := step.do(t)
+		if got != step.want {
+			t.Fatalf("%s: returned %d; want %d", step.name, got, step.want)
+		}
+	}
+	app.waiter.Wait()
+}
@@ -1,19 +1,29 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"errors"
 	"expvar"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-set/v3"
 	"github.com/tomasen/realip"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/logs"
+	"github.com/dusktreader/the-hunt/internal/metrics"
 	"github.com/dusktreader/the-hunt/internal/types"
 	"github.com/dusktreader/the-hunt/internal/validator"
 )
@@ -29,6 +39,39 @@ func chainMiddleware(
 	return ret
 }
 
+const requestIDHeader = "X-Request-ID"
+
+// requestID ties together everything that happens for one request. An ID sent by the client or a proxy in front of
+// the API is kept so that the request can be followed across services. Otherwise, a new one is generated. Either way,
+// it's echoed back in the response.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(logs.WithRequestID(r.Context(), id))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID keeps client-supplied IDs short and free of anything that could mangle a log line or header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.ContainsRune("-_.:", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -47,53 +90,199 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
+// limitKey identifies the client that a request counts against. Authenticated requests are limited per API key or
+// per user so that everyone behind a shared NAT doesn't share one limit. Anonymous requests are limited per IP
+// address.
+func (app *application) limitKey(r *http.Request) (string, bool) {
+	if k := app.contextGetApiKey(r); k != nil {
+		return fmt.Sprintf("key:%d", k.ID), true
+	}
+	if u := app.contextGetUser(r, true); u != nil && !u.IsAnonymous() {
+		return fmt.Sprintf("user:%d", u.ID), true
+	}
+	return "ip:" + realip.FromRequest(r), false
+}
+
+// checkRateLimit counts the request against the limiter and sets the RateLimit-* headers. If the limit is exceeded,
+// the error response is written and false is returned.
+func (app *application) checkRateLimit(
+	w http.ResponseWriter,
+	r *http.Request,
+	limiter data.RateLimiter,
+	policy string,
+) bool {
+	key, _ := app.limitKey(r)
+	res, err := limiter.Allow(r.Context(), key)
+	if err != nil {
+		// Don't take the whole API down with the limiter. Let the request through instead.
+		slog.ErrorContext(r.Context(), "Couldn't check rate limit. Allowing request", "error", err)
+		return true
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+	if !res.Allowed {
+		slog.DebugContext(r.Context(), "Rate limit exceeded", "key", key, "policy", policy)
+		metrics.RateLimitRejections.WithLabelValues(policy).Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+		app.rateLimitExceededResponse(w, r)
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// limitIP applies a generous per-IP policy before the request is authenticated. Without it, requests with bad
+// credentials never reach rateLimit and could guess at tokens and API keys as fast as the server answers.
+func (app *application) limitIP(next http.Handler) http.Handler {
+	if app.config.APIEnv.IsDev() || !app.config.LimitEnabled {
+		return next
+	}
+
+	limiter := data.NewRateLimiter(app.config, app.redis, data.LimitPolicy{
+		Name:  "ip",
+		Limit: app.config.LimitIPRPS,
+		Burst: app.config.LimitIPBurst,
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.checkRateLimit(w, r, limiter, "ip") {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit applies the default policies to every request. Authenticated clients get their own, more generous policy.
 func (app *application) rateLimit(next http.Handler) http.Handler {
-	if app.config.APIEnv.IsDev() {
+	if app.config.APIEnv.IsDev() || !app.config.LimitEnabled {
 		return next
 	}
 
-	clients := data.NewClientMap(app.config)
-	go clients.CleanCycle()
+	anonymous := data.NewRateLimiter(app.config, app.redis, data.LimitPolicy{
+		Name:  "anonymous",
+		Limit: app.config.LimitRPS,
+		Burst: app.config.LimitBurst,
+	})
+	authenticated := data.NewRateLimiter(app.config, app.redis, data.LimitPolicy{
+		Name:  "authenticated",
+		Limit: app.config.LimitUserRPS,
+		Burst: app.config.LimitUserBurst,
+	})
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.LimitEnabled {
-			if !clients.IsIpAllowed(realip.FromRequest(r)) {
-				app.rateLimitExceededResponse(w, r)
-				return
-			}
+		limiter, policy := anonymous, "anonymous"
+		if _, ok := app.limitKey(r); ok {
+			limiter, policy = authenticated, "authenticated"
+		}
+
+		if !app.checkRateLimit(w, r, limiter, policy) {
+			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// limitRoutes returns a wrapper that applies a stricter policy to routes on top of the default policies. The limiter is
+// built once, so every route wrapped by the same wrapper draws from one budget no matter which store keeps it.
+func (app *application) limitRoutes(p data.LimitPolicy) func(http.HandlerFunc) http.HandlerFunc {
+	if app.config.APIEnv.IsDev() || !app.config.LimitEnabled {
+		return func(next http.HandlerFunc) http.HandlerFunc { return next }
+	}
+
+	limiter := data.NewRateLimiter(app.config, app.redis, p)
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !app.checkRateLimit(w, r, limiter, p.Name) {
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// touchInterval is how stale last_used_at may get before a request records its use again. It only needs to be precise
+// enough to show when a session or key was last active, so most requests can skip the write.
+const touchInterval = time.Minute
+
+func needsTouch(lastUsed *time.Time) bool {
+	return lastUsed == nil || time.Since(*lastUsed) >= touchInterval
+}
+
+// touchLog remembers when signed tokens last recorded their use. Signed tokens are verified without reading their row,
+// so this stands in for the last_used_at that needsTouch would otherwise check. Every instance keeps its own log, so a
+// token may be touched once per interval by each instance that serves it.
+type touchLog struct {
+	mutex sync.Mutex
+	last  map[int64]time.Time
+}
+
+// due reports whether the use of the token should be recorded now and, if so, notes that it was. Entries are dropped
+// once they can no longer hold a touch back.
+func (tl *touchLog) due(id int64) bool {
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+
+	if last, ok := tl.last[id]; ok && !needsTouch(&last) {
+		return false
+	}
+	if tl.last == nil {
+		tl.last = make(map[int64]time.Time)
+	}
+	for other, last := range tl.last {
+		if needsTouch(&last) {
+			delete(tl.last, other)
+		}
+	}
+	tl.last[id] = time.Now()
+	return true
+}
+
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		slog.Debug("Authenticating request")
+		slog.DebugContext(r.Context(), "Authenticating request")
 
 		w.Header().Add("Vary", "Authorization")
 
-		slog.Debug("Checking for authorization header")
+		slog.DebugContext(r.Context(), "Checking for authorization header")
 		authorizationHeader := r.Header.Get("Authorization")
 		if authorizationHeader == "" {
-			slog.Debug("No authorization header provided. Binding AnonymousUser to the request")
+			slog.DebugContext(r.Context(), "No authorization header provided. Binding AnonymousUser to the request")
 			r = app.contextSetUser(r, types.AnonymousUser)
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		slog.Debug("Parsing token from auth header")
+		slog.DebugContext(r.Context(), "Parsing token from auth header")
 		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) == 2 && headerParts[0] == "ApiKey" {
+			app.authenticateApiKey(next, w, r, types.PlainToken(headerParts[1]))
+			return
+		}
 		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			slog.Debug("Bad header", "parts", headerParts)
+			slog.DebugContext(r.Context(), "Bad header", "parts", headerParts)
 			app.invalidTokenResponse(w, r, types.ScopeAuthentication)
 			return
 		}
 
+		if app.jwt != nil && strings.Count(headerParts[1], ".") == 2 {
+			app.authenticateJWT(next, w, r, headerParts[1])
+			return
+		}
+
 		plainToken := types.PlainToken(headerParts[1])
-		slog.Debug("Parsed token", "token", plainToken)
+		slog.DebugContext(r.Context(), "Parsed token", "token", plainToken)
 
-		slog.Debug("Validating token")
+		slog.DebugContext(r.Context(), "Validating token")
 		v := validator.New()
 		plainToken.Validate(v)
 		if !v.Valid() {
@@ -101,8 +290,8 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		slog.Debug("Looking up token in database")
-		t, err := app.models.Token.GetOne(plainToken, types.ScopeAuthentication)
+		slog.DebugContext(r.Context(), "Looking up token in database")
+		t, err := app.models.Token.GetOne(r.Context(), plainToken, types.ScopeAuthentication)
 		if err != nil {
 			switch {
 			case errors.Is(err, types.ErrRecordNotFound):
@@ -113,52 +302,186 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		if t.IsAdmin {
-			slog.Debug("Token is admin token. Binding admin to the request")
-			r = app.contextSetAdmin(r)
-		} else {
-			slog.Debug("Token is not admin token. Fetching and binding user", "id", t.UserID)
-			u, err := app.models.User.GetOne(t.UserID)
-			if err != nil {
-				switch {
-				case errors.Is(err, types.ErrRecordNotFound):
-					app.notFoundResponse(w, r, t.UserID)
-				default:
-					app.serverErrorResponse(w, r, err, "Couldn't find user for token")
-				}
-				return
-			}
+		slog.DebugContext(r.Context(), "Binding token to the request", "id", t.ID)
+		r = app.contextSetToken(r, t)
 
-			slog.Debug("Authenticated user from auth token. Binding user to the request", "user", u)
-			r = app.contextSetUser(r, u)
+		if needsTouch(t.LastUsedAt) {
+			touch := func(ctx context.Context) error { return app.models.Token.Touch(ctx, t.ID) }
+			app.background(r.Context(), touch)
+		}
 
-			slog.Debug("Binding user permissions")
-			perms, err := app.models.Permission.GetForUser(t.UserID)
-			if err != nil {
-				app.serverErrorResponse(w, r, err, "Couldn't retrieve user permissions")
-				return
+		slog.DebugContext(r.Context(), "Fetching and binding user", "id", t.UserID)
+		u, err := app.models.User.GetOne(r.Context(), t.UserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, types.ErrRecordNotFound):
+				app.notFoundResponse(w, r, t.UserID)
+			default:
+				app.serverErrorResponse(w, r, err, "Couldn't find user for token")
 			}
-			r = app.contextSetPerms(r, perms)
+			return
 		}
+
+		slog.DebugContext(r.Context(), "Authenticated user from auth token. Binding user to the request", "user", u)
+		r = app.contextSetUser(r, u)
+
+		if u.IsAdmin {
+			slog.DebugContext(r.Context(), "User is an admin. Binding admin to the request")
+			r = app.contextSetAdmin(r)
+		}
+
+		slog.DebugContext(r.Context(), "Binding user permissions")
+		perms, err := app.models.Permission.GetForUser(r.Context(), t.UserID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve user permissions")
+			return
+		}
+		r = app.contextSetPerms(r, perms)
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// authenticateJWT binds the user described by a signed access token to the request without touching the database.
+// Because the user comes from the token, changes to it only apply once the token is refreshed. Changing the user's
+// permissions or roles revokes their tokens instead, so those apply right away.
+func (app *application) authenticateJWT(next http.Handler, w http.ResponseWriter, r *http.Request, raw string) {
+	slog.DebugContext(r.Context(), "Verifying signed token")
+	c, err := app.jwt.Verify(raw)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Signed token is not valid", "error", err)
+		app.invalidTokenResponse(w, r, types.ScopeAuthentication)
+		return
+	}
+
+	if app.denylist.Contains(c.Family) {
+		slog.DebugContext(r.Context(), "Signed token has been revoked", "id", c.TokenID)
+		app.invalidTokenResponse(w, r, types.ScopeAuthentication)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Binding token to the request", "id", c.TokenID)
+	r = app.contextSetToken(r, &types.Token{
+		ID:        c.TokenID,
+		UserID:    c.UserID,
+		ExpiresAt: c.ExpiresAt,
+		Scope:     types.ScopeAuthentication,
+		Family:    c.Family,
+	})
+
+	if app.touches.due(c.TokenID) {
+		touch := func(ctx context.Context) error { return app.models.Token.Touch(ctx, c.TokenID) }
+		app.background(r.Context(), touch)
+	}
+
+	slog.DebugContext(r.Context(), "Binding user from signed token", "id", c.UserID)
+	r = app.contextSetUser(r, &types.User{
+		ID:        c.UserID,
+		Email:     c.Email,
+		Activated: true,
+		IsAdmin:   c.Admin,
+	})
+
+	if c.Admin {
+		slog.DebugContext(r.Context(), "User is an admin. Binding admin to the request")
+		r = app.contextSetAdmin(r)
+	}
+
+	r = app.contextSetPerms(r, types.NewPermissionSet(c.Perms...))
+
+	next.ServeHTTP(w, r)
+}
+
+// authenticateApiKey binds the key's owner to the request. Only the permissions that the key was granted and that the
+// owner still holds are bound so that a key can never do more than its owner. Keys never carry admin status, even when
+// their owner is an admin.
+func (app *application) authenticateApiKey(
+	next http.Handler,
+	w http.ResponseWriter,
+	r *http.Request,
+	plainKey types.PlainToken,
+) {
+	slog.DebugContext(r.Context(), "Validating API key")
+	v := validator.New()
+	plainKey.Validate(v)
+	if !v.Valid() {
+		app.invalidApiKeyResponse(w, r)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Looking up API key in database")
+	k, err := app.models.ApiKey.GetOne(r.Context(), plainKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.invalidApiKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't parse API key")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Binding API key to the request", "id", k.ID)
+	r = app.contextSetApiKey(r, k)
+
+	if needsTouch(k.LastUsedAt) {
+		touch := func(ctx context.Context) error { return app.models.ApiKey.Touch(ctx, k.ID) }
+		app.background(r.Context(), touch)
+	}
+
+	slog.DebugContext(r.Context(), "Fetching and binding user for API key", "id", k.UserID)
+	u, err := app.models.User.GetOne(r.Context(), k.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.invalidApiKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't find user for API key")
+		}
+		return
+	}
+	r = app.contextSetUser(r, u)
+
+	slog.DebugContext(r.Context(), "Binding API key permissions")
+	perms, err := app.models.Permission.GetForUser(r.Context(), k.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve user permissions")
+		return
+	}
+	r = app.contextSetPerms(r, perms.Intersect(k.PermissionSet()).(*types.PermissionSet))
+
+	next.ServeHTTP(w, r)
+}
+
 func (app *application) requireAuthorization(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		slog.Debug("Requiring authorization for request")
+		slog.DebugContext(r.Context(), "Requiring authorization for request")
 
 		isAdmin := app.contextGetAdmin(r, true)
 
 		if !isAdmin {
-			slog.Debug("Request was not made with admin token. Checking for user token")
+			slog.DebugContext(r.Context(), "Request was not made by an admin. Checking for user")
 			user := app.contextGetUser(r, true)
 
 			if user.IsAnonymous() {
 				app.unauthorizedResponse(w, r)
 				return
 			}
-			slog.Debug("User is authorized!")
+			slog.DebugContext(r.Context(), "User is authorized!")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireSession guards the routes where users manage their own account. An API key only carries a subset of its
+// owner's permissions, so it mustn't be able to mint more keys, end sessions or change MFA on the owner's behalf.
+func (app *application) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return app.requireAuthorization(func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetApiKey(r) != nil {
+			slog.DebugContext(r.Context(), "Request was made with an API key but needs a session")
+			app.sessionRequiredResponse(w, r)
+			return
 		}
 
 		next.ServeHTTP(w, r)
@@ -171,24 +494,25 @@ func (app *application) requirePermissions(
 	perms ...types.PermCode,
 ) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		slog.Debug("Checking permissions for request", "perms", perms)
+		slog.DebugContext(r.Context(), "Checking permissions for request", "perms", perms)
 		if len(perms) > 0 {
 
 			isAdmin := app.contextGetAdmin(r, true)
 
 			if !isAdmin {
-				slog.Debug(
-					"Request was not made with admin token. Checking for user permissions",
+				slog.DebugContext(
+					r.Context(),
+					"Request was not made by an admin. Checking for user permissions",
 					"strategy", strategy,
 					"perms", perms,
 				)
 				userPerms := app.contextGetPerms(r, true)
-				slog.Debug("User perms", "perms", userPerms)
+				slog.DebugContext(r.Context(), "User perms", "perms", userPerms)
 				if !types.HasPerms(userPerms, strategy, perms...) {
 					app.forbiddenResponse(w, r)
 					return
 				}
-				slog.Debug("User has required permissions", "perms", userPerms)
+				slog.DebugContext(r.Context(), "User has required permissions", "perms", userPerms)
 			}
 		}
 
@@ -199,7 +523,7 @@ func (app *application) requirePermissions(
 
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		slog.Debug("Dynamically adding CORS headers")
+		slog.DebugContext(r.Context(), "Dynamically adding CORS headers")
 
 		w.Header().Add("Vary", "Origin")
 		w.Header().Add("Vary", "Access-Control-Request-Method")
@@ -224,22 +548,92 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// metrics counts every request along with its latency. It runs outside recoverPanic so that requests which panic are
+// counted with the 500 that they were answered with.
 func (app *application) metrics(next http.Handler) http.Handler {
 	stats := types.NewRequestStats()
-	expvar.Publish("request_stats", expvar.Func(func() any {
-		return stats
-	}))
+	// A name can only be published once per process. Only the first chain's stats are served, which is the only chain
+	// outside of tests.
+	if expvar.Get("request_stats") == nil {
+		expvar.Publish("request_stats", expvar.Func(func() any {
+			return stats
+		}))
+	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		slog.Debug("Adding request metrics")
+		slog.DebugContext(r.Context(), "Adding request metrics")
 
 		start := time.Now()
 		stats.AddRequest()
 
+		r, route := app.contextSetRoute(r)
+
 		mw := newMetricsResponseWriter(w)
 		next.ServeHTTP(mw, r)
 
+		elapsed := time.Since(start)
 		stats.AddResponse(mw.statusCode)
-		stats.AddTime(time.Since(start))
+		stats.AddTime(elapsed)
+
+		if *route == "" {
+			*route = metrics.Unmatched
+		}
+		labels := []string{*route, metrics.Method(r.Method), strconv.Itoa(mw.statusCode)}
+		metrics.Requests.WithLabelValues(labels...).Inc()
+		metrics.RequestDuration.WithLabelValues(labels...).Observe(elapsed.Seconds())
+	})
+}
+
+// logRequest writes one access log line for every request once it has been handled. It runs outside recoverPanic so
+// that requests which panic are logged too. Only the path is logged because query strings can carry secrets, like the
+// code and state of an OIDC callback.
+func (app *application) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, holder := app.contextSetRoute(r)
+		mw := newMetricsResponseWriter(w)
+		next.ServeHTTP(mw, r)
+
+		route := metrics.Unmatched
+		if *holder != "" {
+			route = *holder
+		}
+		slog.InfoContext(
+			r.Context(),
+			"Handled request",
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", mw.statusCode,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"ip", realip.FromRequest(r),
+			"user_agent", r.UserAgent(),
+		)
 	})
 }
+
+// trace starts a span for every request. The span continues the caller's trace if the request carries a traceparent
+// header, and it is renamed after the route template once the router has matched one.
+func (app *application) trace(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(
+		next,
+		"http.request",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
+
+// recordRoute notes which route template matched the request so that the metrics middleware can label the request
+// with it and the request's span can be named after it.
+func (app *application) recordRoute(path string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if route := app.contextGetRoute(r); route != nil {
+			*route = path
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + path)
+		span.SetAttributes(semconv.HTTPRoute(path))
+		next(w, r)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+
+	"github.com/dusktreader/the-hunt/migrations"
+)
+
+// newMigrator prepares the embedded migrations to be applied to the database. Migrations take a Postgres advisory lock
+// first, so replicas that start at the same time wait for each other instead of applying the same migration twice.
+func newMigrator(db *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	return goose.NewProvider(
+		goose.DialectPostgres,
+		db,
+		migrations.FS,
+		goose.WithSessionLocker(locker),
+	)
+}
+
+func logMigration(ctx context.Context, r *goose.MigrationResult) {
+	slog.InfoContext(
+		ctx,
+		"Applied migration",
+		"version", r.Source.Version,
+		"path", r.Source.Path,
+		"direction", r.Direction,
+		"duration", r.Duration,
+	)
+}
+
+// migrateUp applies every pending migration.
+func migrateUp(ctx context.Context, migrator *goose.Provider) error {
+	results, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		logMigration(ctx, r)
+	}
+
+	version, err := migrator.GetDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Database is up to date", "version", version, "applied", len(results))
+	return nil
+}
+
+// migrate runs one of the migrate subcommands:
+//
+//	up      apply every pending migration
+//	down    roll back the most recent migration
+//	redo    roll back the most recent migration and apply it again
+//	status  list every migration and whether it has been applied
+func migrate(ctx context.Context, db *sql.DB, command string) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		return migrateUp(ctx, migrator)
+
+	case "down":
+		r, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		logMigration(ctx, r)
+
+	case "redo":
+		r, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		logMigration(ctx, r)
+
+		r, err = migrator.UpByOne(ctx)
+		if err != nil {
+			return err
+		}
+		logMigration(ctx, r)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.State == goose.StateApplied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, s.Source.Path)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q: must be one of up, down, redo or status", command)
+	}
+
+	return nil
+}
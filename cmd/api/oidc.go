@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func (app *application) oidcStartHandler(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.oidcNotConfiguredResponse(w, r)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Starting OIDC login")
+
+	l := types.NewOIDCLogin(app.config.OIDCLoginTTL)
+	err := app.models.OIDC.InsertLogin(r.Context(), l)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't start OIDC login")
+		return
+	}
+
+	http.Redirect(w, r, app.oidc.AuthCodeURL(l), http.StatusFound)
+}
+
+func (app *application) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.oidcNotConfiguredResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+	if e := qs.Get("error"); e != "" {
+		slog.DebugContext(
+			r.Context(),
+			"Identity provider returned an error",
+			"error", e,
+			"description", qs.Get("error_description"),
+		)
+		app.oidcLoginFailedResponse(w, r)
+		return
+	}
+
+	state := qs.Get("state")
+	code := qs.Get("code")
+
+	v := validator.New()
+	v.Check(state != "", "state", "must be provided")
+	v.Check(code != "", "code", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Completing OIDC login")
+
+	l, err := app.models.OIDC.ConsumeLogin(r.Context(), state)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			slog.DebugContext(r.Context(), "No pending OIDC login found for state")
+			app.oidcLoginFailedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve OIDC login")
+		}
+		return
+	}
+
+	id, err := app.oidc.Exchange(r.Context(), code, l)
+	if err != nil {
+		slog.WarnContext(r.Context(), "OIDC code exchange failed", "error", err)
+		app.oidcLoginFailedResponse(w, r)
+		return
+	}
+
+	if !id.EmailVerified {
+		app.emailNotVerifiedResponse(w, r)
+		return
+	}
+
+	id.Email.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	u, err := app.resolveIdentity(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't resolve user for external identity")
+		return
+	}
+
+	app.completeLogin(w, r, u)
+}
+
+// resolveIdentity finds the user for an external identity. An identity that hasn't been seen before is linked to the
+// user with the same email, and a new user is created when there isn't one. Linking takes over a user that was never
+// activated, so it is handed a random password to replace the one that whoever registered the email chose.
+func (app *application) resolveIdentity(ctx context.Context, id *types.Identity) (*types.User, error) {
+	userID, err := app.models.OIDC.GetUserID(ctx, id.Issuer, id.Subject)
+	if err == nil {
+		return app.models.User.GetOne(ctx, userID)
+	} else if !errors.Is(err, types.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	u, err := app.models.User.GetByEmail(ctx, id.Email)
+	switch {
+	case err == nil:
+		slog.DebugContext(ctx, "Linking external identity to existing user", "id", u.ID)
+	case errors.Is(err, types.ErrRecordNotFound):
+		u, err = app.createIdentityUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	hp, err := types.NewHashPW(ctx, types.PlainPW(rand.Text()))
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.models.OIDC.LinkIdentity(ctx, u.ID, id, hp)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// createIdentityUser adds an activated user for an external identity. The user gets a random password that nobody
+// knows; they can set their own through a password reset if they ever want to log in without the provider.
+func (app *application) createIdentityUser(ctx context.Context, id *types.Identity) (*types.User, error) {
+	name := id.Name
+	if name == "" {
+		name, _, _ = strings.Cut(string(id.Email), "@")
+	}
+
+	slog.DebugContext(ctx, "Creating user for external identity", "email", id.Email)
+
+	hp, err := types.NewHashPW(ctx, types.PlainPW(rand.Text()))
+	if err != nil {
+		return nil, err
+	}
+
+	u := &types.User{
+		Name:           name,
+		Email:          id.Email,
+		Activated:      true,
+		HashedPassword: hp,
+	}
+	err = app.models.User.Insert(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.models.Role.AddForUser(ctx, types.SystemUserID, u.ID, app.config.DefaultRoles...)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
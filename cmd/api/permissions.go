@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func (app *application) readManyPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	slog.DebugContext(r.Context(), "Fetching permission list")
+
+	perms, err := app.models.Permission.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve permissions")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"permissions": perms},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize permission data")
+	}
+}
+
+// readUserPermissions confirms that the user exists and then responds with the user's current permissions. It is
+// shared by all of the user permission handlers so that each of them responds with the resulting state.
+func (app *application) readUserPermissions(w http.ResponseWriter, r *http.Request, userID int64) {
+	slog.DebugContext(r.Context(), "Fetching permissions for user", "id", userID)
+
+	perms, err := app.models.Permission.GetForUser(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve user permissions")
+		return
+	}
+
+	codes := perms.Slice()
+	slices.Sort(codes)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"permissions": codes},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize permission data")
+	}
+}
+
+// parsePermissionsTarget reads the user ID from the route and checks that the user exists. If anything is wrong, the
+// error response is written and false is returned.
+func (app *application) parsePermissionsTarget(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := app.parseUserIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return 0, false
+	}
+
+	_, err = app.models.User.GetOne(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, userID)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve user")
+		}
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// readPermissionChanges reads and validates the list of permission codes in the request body. If anything is wrong,
+// the error response is written and false is returned.
+func (app *application) readPermissionChanges(w http.ResponseWriter, r *http.Request) ([]types.PermCode, bool) {
+	var input struct {
+		Permissions []types.PermCode `json:"permissions"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	known, err := app.models.Permission.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve permissions")
+		return nil, false
+	}
+
+	v := validator.New()
+	types.ValidatePermCodes(v, input.Permissions, types.NewPermissionSet(known...))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return nil, false
+	}
+
+	return input.Permissions, true
+}
+
+func (app *application) readUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.parsePermissionsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	app.readUserPermissions(w, r, userID)
+}
+
+func (app *application) replaceUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.parsePermissionsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	perms, ok := app.readPermissionChanges(w, r)
+	if !ok {
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Replacing permissions for user", "id", userID, "perms", perms)
+
+	err := app.models.Permission.ReplaceForUser(r.Context(), app.contextGetActorID(r), userID, perms...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't replace user permissions")
+		return
+	}
+
+	err = app.endSignedSessions(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't revoke existing tokens")
+		return
+	}
+
+	app.readUserPermissions(w, r, userID)
+}
+
+func (app *application) revokeUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.parsePermissionsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	perms, ok := app.readPermissionChanges(w, r)
+	if !ok {
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Revoking permissions for user", "id", userID, "perms", perms)
+
+	err := app.models.Permission.RevokeForUser(r.Context(), app.contextGetActorID(r), userID, perms...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't revoke user permissions")
+		return
+	}
+
+	err = app.endSignedSessions(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't revoke existing tokens")
+		return
+	}
+
+	app.readUserPermissions(w, r, userID)
+}
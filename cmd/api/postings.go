@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+type postingInput struct {
+	Title     string             `json:"title"`
+	URL       string             `json:"url"`
+	Location  string             `json:"location"`
+	Remote    types.RemotePolicy `json:"remote"`
+	SalaryMin *int64             `json:"salary_min"`
+	SalaryMax *int64             `json:"salary_max"`
+	PostedAt  *time.Time         `json:"posted_at"`
+	ClosedAt  *time.Time         `json:"closed_at"`
+}
+
+func (in postingInput) apply(p *types.Posting) {
+	p.Title = in.Title
+	p.URL = in.URL
+	p.Location = in.Location
+	p.Remote = in.Remote
+	p.SalaryMin = in.SalaryMin
+	p.SalaryMax = in.SalaryMax
+	p.PostedAt = in.PostedAt
+	p.ClosedAt = in.ClosedAt
+
+	if p.Remote == "" {
+		p.Remote = types.RemoteOnsite
+	}
+}
+
+func (app *application) createPostingHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	companyID, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	var input postingInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Creating a new posting", "company_id", companyID, "input", input)
+
+	v := validator.New()
+
+	p := &types.Posting{CompanyID: companyID}
+	input.apply(p)
+
+	slog.DebugContext(r.Context(), "Validating new posting")
+
+	p.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Inserting new posting into database")
+
+	err = app.models.Posting.Insert(r.Context(), tenant, p)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, companyID)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't add posting")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Serializing response")
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/postings/%d", p.ID))
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"posting": p},
+		StatusCode: http.StatusCreated,
+		Headers:    headers,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize posting data")
+		return
+	}
+}
+
+func (app *application) readPostingHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+	slog.DebugContext(r.Context(), "Fetching posting details", "id", id)
+
+	p, err := app.models.Posting.GetOne(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve posting")
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Retrieved posting", "Posting", *p)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"posting": p},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize posting data")
+	}
+}
+
+func (app *application) readManyPostingsHandler(w http.ResponseWriter, r *http.Request) {
+	app.readPostings(w, r)
+}
+
+func (app *application) readCompanyPostingsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	companyID, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Checking that company is visible", "company_id", companyID)
+	_, err = app.models.Company.GetOne(r.Context(), tenant, companyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, companyID)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't retrieve company")
+		}
+		return
+	}
+
+	app.readPostings(w, r, companyID)
+}
+
+func (app *application) readPostings(w http.ResponseWriter, r *http.Request, companyID ...int64) {
+	tenant := app.contextGetTenant(r)
+
+	slog.DebugContext(r.Context(), "Fetching posting list", "company_id", companyID)
+
+	qs := r.URL.Query()
+	v := validator.New()
+	filters := data.ParseFilters(
+		qs,
+		v,
+		data.FilterConstraints{
+			Search: data.PostingSearchFields.Check,
+			Sort:   data.PostingSortFields.Check,
+		},
+	)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Retrieved filters", "filters", filters)
+
+	postings, metadata, err := app.models.Posting.GetMany(r.Context(), tenant, filters, companyID...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve postings")
+		return
+	}
+	slog.DebugContext(r.Context(), "Fetched postings", "metadata", metadata)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		StatusCode: http.StatusOK,
+		Envelope: data.Envelope{
+			"postings": postings,
+			"metadata": metadata,
+		},
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize posting data")
+	}
+}
+
+func (app *application) updatePostingHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Updating posting", "id", id)
+
+	p, err := app.models.Posting.GetOne(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Retrieved posting", "Posting", *p)
+
+	var input postingInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Updating posting with request payload", "id", id, "input", input)
+
+	input.apply(p)
+
+	slog.DebugContext(r.Context(), "Validating updated posting", "id", id, "posting", p)
+
+	v := validator.New()
+	p.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Updating posting in database")
+
+	err = app.models.Posting.Update(r.Context(), tenant, p)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't update posting")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Serializing response")
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"posting": p},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize posting data")
+		return
+	}
+}
+
+func (app *application) updatePartialPostingHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Partially updating posting", "id", id)
+
+	// The whole posting is loaded so that the cross-field checks can be made against the merged result.
+	current, err := app.models.Posting.GetOne(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Retrieved version", "Version", current.Version)
+
+	pp := types.PartialPosting{}
+
+	err = app.readJSON(w, r, &pp)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	slog.DebugContext(r.Context(), "Updating posting with request payload", "id", id, "input", pp)
+
+	slog.DebugContext(r.Context(), "Validating partial posting", "id", id, "partial_posting", pp)
+	v := validator.New()
+	pp.Validate(v)
+	if v.Valid() {
+		pp.Apply(current)
+		current.Validate(v)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Updating posting in database")
+	p, err := app.models.Posting.PartialUpdate(r.Context(), tenant, id, current.Version, &pp)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't update posting")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Serializing response")
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"posting": p},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize posting data")
+		return
+	}
+}
+
+func (app *application) deletePostingHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := app.contextGetTenant(r)
+
+	id, err := app.parseIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+	slog.DebugContext(r.Context(), "Deleting posting", "id", id)
+
+	err = app.models.Posting.Delete(r.Context(), tenant, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't delete posting")
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Deleted posting", "id", id)
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"message": "Posting deleted successfully"},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
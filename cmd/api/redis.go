@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+)
+
+func openRedis(cfg data.Config) (*redis.Client, error) {
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+	rdb := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = rdb.Ping(ctx).Err()
+	if err != nil {
+		rdb.Close()
+		return nil, err
+	}
+
+	return rdb, nil
+}
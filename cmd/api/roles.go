@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func (app *application) readManyRolesHandler(w http.ResponseWriter, r *http.Request) {
+	slog.DebugContext(r.Context(), "Fetching role list")
+
+	roles, err := app.models.Role.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve roles")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"roles": roles},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize role data")
+	}
+}
+
+func (app *application) readUserRoles(w http.ResponseWriter, r *http.Request, userID int64) {
+	slog.DebugContext(r.Context(), "Fetching roles for user", "id", userID)
+
+	roles, err := app.models.Role.GetForUser(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve user roles")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"roles": roles},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize role data")
+	}
+}
+
+func (app *application) readUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.parsePermissionsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	app.readUserRoles(w, r, userID)
+}
+
+func (app *application) replaceUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.parsePermissionsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Roles []string `json:"roles"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	roles, err := app.models.Role.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve roles")
+		return
+	}
+
+	known := make([]string, 0, len(roles))
+	for _, role := range roles {
+		known = append(known, role.Name)
+	}
+
+	v := validator.New()
+	types.ValidateRoleNames(v, input.Roles, known)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Replacing roles for user", "id", userID, "roles", input.Roles)
+
+	err = app.models.Role.ReplaceForUser(r.Context(), app.contextGetActorID(r), userID, input.Roles...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't replace user roles")
+		return
+	}
+
+	err = app.endSignedSessions(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't revoke existing tokens")
+		return
+	}
+
+	app.readUserRoles(w, r, userID)
+}
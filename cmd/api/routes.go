@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/dusktreader/the-hunt/internal/data"
 	"github.com/dusktreader/the-hunt/internal/types"
 	"github.com/julienschmidt/httprouter"
 )
@@ -17,17 +18,38 @@ type Route struct {
 
 type RouteList []Route
 
-func (app *application) routes() http.Handler {
-	router := httprouter.New()
-
-	router.NotFound = http.HandlerFunc(app.routeNotFoundResponse)
-	router.MethodNotAllowed = http.HandlerFunc(app.notAllowedResponse)
-
-	// auth := app.requireAuthorization
+// routeList describes every route that the API serves along with the permissions and limits that guard it.
+func (app *application) routeList() RouteList {
+	auth := app.requireAuthorization
+	session := app.requireSession
 	perms := app.requirePermissions
 
-	routes := RouteList{
+	// Routes that share a policy share its budget. Logging in through OIDC counts against the same limit as logging in
+	// with a password, for example.
+	login := app.limitRoutes(data.LimitPolicy{
+		Name:  "login",
+		Limit: app.config.LimitLoginRPS,
+		Burst: app.config.LimitLoginBurst,
+	})
+	activation := app.limitRoutes(data.LimitPolicy{
+		Name:  "activation",
+		Limit: app.config.LimitActivationRPS,
+		Burst: app.config.LimitActivationBurst,
+	})
+	refresh := app.limitRoutes(data.LimitPolicy{
+		Name:  "refresh",
+		Limit: app.config.LimitRefreshRPS,
+		Burst: app.config.LimitRefreshBurst,
+	})
+	reset := app.limitRoutes(data.LimitPolicy{
+		Name:  "reset",
+		Limit: app.config.LimitResetRPS,
+		Burst: app.config.LimitResetBurst,
+	})
+
+	return RouteList{
 		{http.MethodGet, "/health", app.healthHandler},
+		{http.MethodGet, "/.well-known/jwks.json", app.jwksHandler},
 
 		{http.MethodPost, "/v1/companies", perms(app.createCompanyHandler, types.All, types.CompanyWrite)},
 		{http.MethodGet, "/v1/companies", perms(app.readManyCompaniesHandler, types.All, types.CompanyRead)},
@@ -35,21 +57,76 @@ func (app *application) routes() http.Handler {
 		{http.MethodPut, "/v1/companies/:id", perms(app.updateCompanyHandler, types.All, types.CompanyWrite)},
 		{http.MethodPatch, "/v1/companies/:id", perms(app.updatePartialCompanyHandler, types.All, types.CompanyWrite)},
 		{http.MethodDelete, "/v1/companies/:id", perms(app.deleteCompanyHandler, types.All, types.CompanyWrite)},
+		{http.MethodPost, "/v1/companies/:id/postings", perms(app.createPostingHandler, types.All, types.PostingWrite)},
+		{http.MethodGet, "/v1/companies/:id/postings", perms(app.readCompanyPostingsHandler, types.All, types.PostingRead)},
+
+		{http.MethodGet, "/v1/postings", perms(app.readManyPostingsHandler, types.All, types.PostingRead)},
+		{http.MethodGet, "/v1/postings/:id", perms(app.readPostingHandler, types.All, types.PostingRead)},
+		{http.MethodPut, "/v1/postings/:id", perms(app.updatePostingHandler, types.All, types.PostingWrite)},
+		{http.MethodPatch, "/v1/postings/:id", perms(app.updatePartialPostingHandler, types.All, types.PostingWrite)},
+		{http.MethodDelete, "/v1/postings/:id", perms(app.deletePostingHandler, types.All, types.PostingWrite)},
+
+		{http.MethodPost, "/v1/applications", perms(app.createApplicationHandler, types.All, types.ApplicationWrite)},
+		{http.MethodGet, "/v1/applications", perms(app.readManyApplicationsHandler, types.All, types.ApplicationRead)},
+		{http.MethodGet, "/v1/applications/:id", perms(app.readApplicationHandler, types.All, types.ApplicationRead)},
+		{http.MethodDelete, "/v1/applications/:id", perms(app.deleteApplicationHandler, types.All, types.ApplicationWrite)},
+		{http.MethodPost, "/v1/applications/:id/transitions", perms(app.createApplicationTransitionHandler, types.All, types.ApplicationWrite)},
+		{http.MethodGet, "/v1/applications/:id/transitions", perms(app.readApplicationTransitionsHandler, types.All, types.ApplicationRead)},
 
 		{http.MethodPost, "/v1/users", perms(app.createUserHandler, types.All, types.UserWrite)},
 		{http.MethodGet, "/v1/users", perms(app.readManyUsersHandler, types.All, types.UserRead)},
 		{http.MethodGet, "/v1/users/:id", perms(app.readUserHandler, types.All, types.UserRead)},
-		{http.MethodPut, "/v1/users/:id", perms(app.updateUserHandler, types.All, types.UserWrite)},
+		// httprouter panics if /v1/users/password is registered next to /v1/users/:id, so the password reset has to
+		// be dispatched from inside the PUT /v1/users/:id route. IDs are always numeric, so they can't collide.
+		{http.MethodPut, "/v1/users/:id", app.staticParam(
+			"id",
+			map[string]http.HandlerFunc{"password": reset(app.resetPasswordHandler)},
+			perms(app.updateUserHandler, types.All, types.UserWrite),
+		)},
 		{http.MethodPatch, "/v1/users/:id", perms(app.updatePartialUserHandler, types.All, types.UserWrite)},
 		{http.MethodDelete, "/v1/users/:id", perms(app.deleteUserHandler, types.All, types.UserWrite)},
-		{http.MethodPost, "/v1/users/activate", app.activateUserHandler},
+		{http.MethodPost, "/v1/users/:id", app.staticParam(
+			"id",
+			map[string]http.HandlerFunc{"activate": activation(app.activateUserHandler)},
+			app.notAllowedResponse,
+		)},
+		{http.MethodGet, "/v1/users/:id/sessions", session(app.readSessionsHandler)},
+		{http.MethodDelete, "/v1/users/:id/sessions/:session_id", session(app.deleteSessionHandler)},
+		{http.MethodGet, "/v1/users/:id/api-keys", session(app.readApiKeysHandler)},
+		{http.MethodPost, "/v1/users/:id/api-keys", session(app.createApiKeyHandler)},
+		{http.MethodDelete, "/v1/users/:id/api-keys/:key_id", session(app.deleteApiKeyHandler)},
+		{http.MethodPost, "/v1/users/:id/mfa", session(app.enrollMFAHandler)},
+		{http.MethodPost, "/v1/users/:id/mfa/verify", session(app.verifyMFAHandler)},
+		{http.MethodPost, "/v1/users/:id/unlock", perms(app.unlockUserHandler, types.All, types.UserWrite)},
+		{http.MethodGet, "/v1/users/:id/permissions", perms(app.readUserPermissionsHandler, types.All, types.PermissionAdmin)},
+		{http.MethodPut, "/v1/users/:id/permissions", perms(app.replaceUserPermissionsHandler, types.All, types.PermissionAdmin)},
+		{http.MethodDelete, "/v1/users/:id/permissions", perms(app.revokeUserPermissionsHandler, types.All, types.PermissionAdmin)},
+		{http.MethodGet, "/v1/users/:id/roles", perms(app.readUserRolesHandler, types.All, types.PermissionAdmin)},
+		{http.MethodPut, "/v1/users/:id/roles", perms(app.replaceUserRolesHandler, types.All, types.PermissionAdmin)},
 
-		{http.MethodPost, "/v1/login", app.loginHandler},
+		{http.MethodGet, "/v1/permissions", perms(app.readManyPermissionsHandler, types.All, types.PermissionAdmin)},
+		{http.MethodGet, "/v1/roles", perms(app.readManyRolesHandler, types.All, types.PermissionAdmin)},
+
+		{http.MethodPost, "/v1/login", login(app.loginHandler)},
+		{http.MethodPost, "/v1/login/mfa", login(app.loginMFAHandler)},
+		{http.MethodGet, "/v1/auth/oidc/start", login(app.oidcStartHandler)},
+		{http.MethodGet, "/v1/auth/oidc/callback", login(app.oidcCallbackHandler)},
+		{http.MethodPost, "/v1/tokens/activation", activation(app.createActivationTokenHandler)},
+		{http.MethodPost, "/v1/tokens/refresh", refresh(app.refreshTokenHandler)},
+		{http.MethodPost, "/v1/tokens/password-reset", reset(app.createPasswordResetTokenHandler)},
+		{http.MethodDelete, "/v1/tokens/current", auth(app.deleteCurrentTokenHandler)},
 	}
+}
+
+func (app *application) routes() http.Handler {
+	router := httprouter.New()
+
+	router.NotFound = http.HandlerFunc(app.routeNotFoundResponse)
+	router.MethodNotAllowed = http.HandlerFunc(app.notAllowedResponse)
 
 	slog.Debug("Adding routes")
-	for _, r := range routes {
-		router.HandlerFunc(r.method, r.path, r.handler)
+	for _, r := range app.routeList() {
+		router.HandlerFunc(r.method, r.path, app.recordRoute(r.path, r.handler))
 	}
 
 	if app.config.APIEnv.IsDev() {
@@ -58,10 +135,14 @@ func (app *application) routes() http.Handler {
 
 	return chainMiddleware(
 		router,
-		app.recoverPanic,
+		app.trace,
+		app.requestID,
+		app.logRequest,
 		app.metrics,
+		app.recoverPanic,
 		app.enableCORS,
-		app.rateLimit,
+		app.limitIP,
 		app.authenticate,
+		app.rateLimit,
 	)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -10,6 +11,8 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+
+	"github.com/dusktreader/the-hunt/internal/metrics"
 )
 
 func (app *application) serve() error {
@@ -21,6 +24,25 @@ func (app *application) serve() error {
 		ErrorLog:    slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
 	}
 
+	var metricsSrv *http.Server
+	if app.config.MetricsPort != 0 {
+		metricsSrv = &http.Server{
+			Addr:        fmt.Sprintf(":%d", app.config.MetricsPort),
+			Handler:     app.metricsHandler(),
+			IdleTimeout: 5 * time.Second,
+			ReadTimeout: 10 * time.Second,
+			ErrorLog:    slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+		}
+
+		go func() {
+			slog.Info("Starting metrics server", "port", app.config.MetricsPort)
+			err := metricsSrv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Metrics server closed with an unexpected error", "error", err)
+			}
+		}()
+	}
+
 	shutdownError := make(chan error)
 
 	go func() {
@@ -34,6 +56,13 @@ func (app *application) serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		if metricsSrv != nil {
+			err := metricsSrv.Shutdown(ctx)
+			if err != nil {
+				slog.Warn("Couldn't shut down metrics server", "error", err)
+			}
+		}
+
 		err := srv.Shutdown(ctx)
 		if err != nil {
 			shutdownError <- err
@@ -62,3 +91,22 @@ func (app *application) serve() error {
 
 	return nil
 }
+
+// metricsHandler serves the Prometheus metrics. They are kept off of the API port so that they can't be reached from
+// wherever the API is exposed, and they can additionally be guarded by a bearer token.
+func (app *application) metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	handler := metrics.Handler()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		if app.config.MetricsToken != "" {
+			expected := "Bearer " + app.config.MetricsToken
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+	return mux
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func (app *application) readSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.parseUserIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	if !app.isSelfOrAdmin(r, userID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Fetching sessions for user", "id", userID)
+
+	sessions, err := app.models.Token.GetSessionsForUser(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve sessions")
+		return
+	}
+
+	current := app.contextGetToken(r, true)
+	if current != nil {
+		for _, s := range sessions {
+			s.Current = s.ID == current.ID
+		}
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"sessions": sessions},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize session data")
+	}
+}
+
+func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.parseUserIdParam(r)
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	if !app.isSelfOrAdmin(r, userID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	id, err := app.parseIdParam(r, "session_id")
+	if err != nil {
+		app.badIdResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Revoking session", "user_id", userID, "id", id)
+
+	err = app.models.Token.DeleteSession(r.Context(), userID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.notFoundResponse(w, r, id)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't revoke session")
+		}
+		return
+	}
+	slog.DebugContext(r.Context(), "Revoked session", "user_id", userID, "id", id)
+	app.refreshDenylist(r.Context())
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"message": "Session revoked successfully"},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
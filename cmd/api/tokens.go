@@ -1,15 +1,113 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
+	"github.com/go-jose/go-jose/v4"
+	"github.com/tomasen/realip"
+
 	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/jwt"
 	"github.com/dusktreader/the-hunt/internal/types"
 	"github.com/dusktreader/the-hunt/internal/validator"
 )
 
+// generateAuthTokens creates a short-lived access token and a long-lived refresh token. Both record the client that
+// requested them so that they can be identified later in the user's session list.
+func (app *application) generateAuthTokens(r *http.Request, userID int64) (*types.Token, *types.Token) {
+	access := types.GenerateToken(userID, app.config.AuthTTL, types.ScopeAuthentication)
+	refresh := types.GenerateToken(userID, app.config.RefreshTTL, types.ScopeRefresh)
+	for _, t := range []*types.Token{access, refresh} {
+		t.IP = realip.FromRequest(r)
+		t.UserAgent = r.UserAgent()
+	}
+	return access, refresh
+}
+
+// signAccessToken replaces the plaintext of a stored access token with a signed JWT when JWT mode is enabled. The
+// stored token still backs the user's session list and is what gets deleted to revoke the session.
+func (app *application) signAccessToken(ctx context.Context, t *types.Token) error {
+	if app.jwt == nil {
+		return nil
+	}
+
+	u, err := app.models.User.GetOne(ctx, t.UserID)
+	if err != nil {
+		return err
+	}
+
+	perms, err := app.models.Permission.GetForUser(ctx, t.UserID)
+	if err != nil {
+		return err
+	}
+
+	signed, err := app.jwt.Sign(jwt.Claims{
+		TokenID:   t.ID,
+		UserID:    u.ID,
+		Email:     u.Email,
+		Family:    t.Family,
+		Perms:     perms.Slice(),
+		Admin:     u.IsAdmin,
+		ExpiresAt: t.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	t.Plaintext = types.PlainToken(signed)
+	return nil
+}
+
+// endSignedSessions logs the user out everywhere when their permissions change while access tokens are signed. A signed
+// token carries the permissions it was issued with, so without this a revoked permission would keep working until the
+// token expired. Opaque tokens look up permissions on every request and are left alone.
+func (app *application) endSignedSessions(ctx context.Context, userID int64) error {
+	if app.config.TokenMode != types.TokenModeJWT {
+		return nil
+	}
+
+	slog.DebugContext(ctx, "Revoking authentication and refresh tokens for user", "id", userID)
+	for _, scope := range []types.TokenScope{types.ScopeAuthentication, types.ScopeRefresh} {
+		err := app.models.Token.DeleteForUser(ctx, string(scope), userID)
+		if err != nil {
+			return err
+		}
+	}
+	app.refreshDenylist(ctx)
+	return nil
+}
+
+// refreshDenylist reloads the denylist right after tokens are revoked so that this instance stops accepting their
+// signed counterparts immediately instead of on the next scheduled refresh.
+func (app *application) refreshDenylist(ctx context.Context) {
+	if app.denylist == nil {
+		return
+	}
+	err := app.denylist.Refresh(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Couldn't refresh token denylist", "error", err)
+	}
+}
+
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	if app.jwt != nil {
+		jwks = app.jwt.JWKS()
+	}
+
+	err := app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"keys": jwks.Keys},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize keys")
+	}
+}
+
 func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email         types.Email   `json:"email"`
@@ -22,7 +120,7 @@ func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Debug("Creating authentication token", "email", input.Email)
+	slog.DebugContext(r.Context(), "Creating authentication token", "email", input.Email)
 
 	v := validator.New()
 
@@ -34,49 +132,321 @@ func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var userID int64
-	var isAdmin bool
-	if app.config.APIEnv.IsDev() && l.Email == app.config.AdminEmail {
-		if l.Password == app.config.AdminPassword {
-			userID = types.AdminUserID
-			isAdmin = true
-		} else {
+	if !app.checkLockout(w, r, l.Email) {
+		return
+	}
+
+	u, err := app.models.User.GetForLogin(r.Context(), l)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Couldn't retrieve user", "email", l.Email, "error", err)
+		switch {
+		case errors.Is(err, types.ErrUserNotActivated):
+			app.userNotActivatedResponse(w, r)
+		case errors.Is(err, types.ErrRecordNotFound),
+			errors.Is(err, types.ErrPasswordMismatch),
+			errors.Is(err, types.ErrUnauthorized):
+			err = app.recordLoginFailure(r, l.Email)
+			if err != nil {
+				app.serverErrorResponse(w, r, err, "Couldn't record login failure")
+				return
+			}
+			app.unauthorizedResponse(w, r)
+		default:
 			app.serverErrorResponse(w, r, err, "Couldn't authenticate user")
-			return
 		}
+		return
+	}
+
+	app.completeLogin(w, r, u)
+}
+
+// completeLogin finishes the login of an authenticated user. Users with MFA enabled get a challenge instead of a
+// session. The email's failure count is only cleared once a session is issued. Clearing it after the password would
+// let anybody who knows the password reset the count between guesses at the MFA code.
+func (app *application) completeLogin(w http.ResponseWriter, r *http.Request, u *types.User) {
+	mfa, err := app.models.MFA.GetForUser(r.Context(), u.ID)
+	switch {
+	case err == nil && mfa.Enabled:
+		app.startMFAChallenge(w, r, u.ID)
+		return
+	case err != nil && !errors.Is(err, types.ErrRecordNotFound):
+		app.serverErrorResponse(w, r, err, "Couldn't retrieve MFA settings")
+		return
+	}
+
+	err = app.resetLoginFailures(r, u.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't reset login failures")
+		return
+	}
+
+	app.startSession(w, r, u.ID)
+}
+
+// startSession issues a new token family for the user and responds with its access and refresh tokens.
+func (app *application) startSession(w http.ResponseWriter, r *http.Request, userID int64) {
+	access, refresh := app.generateAuthTokens(r, userID)
+	family := types.NewTokenFamily()
+	access.Family = family
+	refresh.Family = family
+
+	err := app.models.Token.InsertFamily(r.Context(), access, refresh)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't authenticate user")
+		return
+	}
+
+	err = app.signAccessToken(r.Context(), access)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't sign access token")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"auth": access, "refresh": refresh},
+		StatusCode: http.StatusCreated,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize data")
+	}
+}
+
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken types.PlainToken `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Refreshing authentication token")
+
+	v := validator.New()
+	input.RefreshToken.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	access, refresh := app.generateAuthTokens(r, 0)
+
+	err = app.models.Token.Rotate(r.Context(), input.RefreshToken, access, refresh)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r, types.ScopeRefresh)
+		case errors.Is(err, types.ErrTokenReused):
+			slog.WarnContext(r.Context(), "Rotated refresh token was presented again. Revoked its token family")
+			app.refreshDenylist(r.Context())
+			app.invalidTokenResponse(w, r, types.ScopeRefresh)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't refresh token")
+		}
+		return
+	}
+
+	err = app.signAccessToken(r.Context(), access)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't sign access token")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"auth": access, "refresh": refresh},
+		StatusCode: http.StatusCreated,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize data")
+	}
+}
+
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email types.Email `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Processing request for password reset", "email", input.Email)
+
+	v := validator.New()
+	input.Email.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	if !app.allowMail(r.Context(), input.Email) {
+		app.rateLimitExceededResponse(w, r)
+		return
+	}
+
+	// The response is the same whether or not the account exists so that this endpoint can't be used to enumerate
+	// users.
+	jr := &data.JSONResponse{
+		Envelope: data.Envelope{
+			"message": "If an account exists for that email, you will receive password reset instructions shortly",
+		},
+		StatusCode: http.StatusAccepted,
+	}
+
+	u, err := app.models.User.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			slog.DebugContext(r.Context(), "No user found for password reset", "email", input.Email)
+			err = app.writeJSON(w, jr)
+			if err != nil {
+				app.serverErrorResponse(w, r, err, "Failed to serialize response")
+			}
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't process password reset request")
+		}
+		return
+	}
+
+	if !u.Activated {
+		slog.DebugContext(r.Context(), "User is not activated. Skipping password reset", "id", u.ID)
 	} else {
-		u, err := app.models.User.GetForLogin(l)
+		t, err := app.models.Token.New(r.Context(), u.ID, app.config.PasswordResetTTL, types.ScopePasswordReset)
 		if err != nil {
-			slog.Debug("Couldn't retrieve user", "email", l.Email, "error", err)
-			switch {
-			case errors.Is(err, types.ErrUserNotActivated):
-				app.userNotActivatedResponse(w, r)
-			case errors.Is(err, types.ErrUnauthorized):
-				app.unauthorizedResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err, "Couldn't authenticate user")
+			app.serverErrorResponse(w, r, err, "Couldn't create password reset token")
+			return
+		}
+
+		templateData := map[string]any{
+			"user":  u,
+			"token": t,
+		}
+
+		slog.DebugContext(r.Context(), "Starting mail sender go routine")
+		send := func(ctx context.Context) error {
+			return app.mailer.Send(ctx, u.Email, "password_reset.tmpl", templateData)
+		}
+		app.background(r.Context(), send)
+	}
+
+	err = app.writeJSON(w, jr)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
+
+func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email types.Email `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Processing request for new activation token", "email", input.Email)
+
+	v := validator.New()
+	input.Email.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	if !app.allowMail(r.Context(), input.Email) {
+		app.rateLimitExceededResponse(w, r)
+		return
+	}
+
+	// The response is the same whether or not the account exists so that this endpoint can't be used to enumerate
+	// users.
+	jr := &data.JSONResponse{
+		Envelope: data.Envelope{
+			"message": "If an inactive account exists for that email, you will receive activation instructions shortly",
+		},
+		StatusCode: http.StatusAccepted,
+	}
+
+	u, err := app.models.User.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			slog.DebugContext(r.Context(), "No user found for activation", "email", input.Email)
+			err = app.writeJSON(w, jr)
+			if err != nil {
+				app.serverErrorResponse(w, r, err, "Failed to serialize response")
 			}
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't process activation request")
+		}
+		return
+	}
+
+	if u.Activated {
+		slog.DebugContext(r.Context(), "User is already activated. Skipping activation token", "id", u.ID)
+	} else {
+		slog.DebugContext(r.Context(), "Replacing activation tokens for user", "id", u.ID)
+		err = app.models.Token.DeleteForUser(r.Context(), string(types.ScopeActivation), u.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err, "Couldn't create activation token")
+			return
+		}
+
+		t, err := app.models.Token.New(r.Context(), u.ID, app.config.ActivationTTL, types.ScopeActivation)
+		if err != nil {
+			app.serverErrorResponse(w, r, err, "Couldn't create activation token")
 			return
 		}
-		userID = u.ID
+
+		templateData := map[string]any{
+			"user":  u,
+			"token": t,
+		}
+
+		slog.DebugContext(r.Context(), "Starting mail sender go routine")
+		send := func(ctx context.Context) error {
+			return app.mailer.Send(ctx, u.Email, "user_activation.tmpl", templateData)
+		}
+		app.background(r.Context(), send)
 	}
 
-	token, err := app.models.Token.New(
-		userID,
-		app.config.AuthTTL,
-		types.ScopeAuthentication,
-		isAdmin,
-	)
+	err = app.writeJSON(w, jr)
 	if err != nil {
-		app.serverErrorResponse(w, r, err, "Couldn't authenticate user")
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+	}
+}
+
+func (app *application) deleteCurrentTokenHandler(w http.ResponseWriter, r *http.Request) {
+	t := app.contextGetToken(r, true)
+	if t == nil {
+		app.badRequestResponse(w, r, fmt.Errorf("the request was not made with a bearer token"))
+		return
+	}
+	slog.DebugContext(r.Context(), "Revoking current token", "id", t.ID)
+
+	err := app.models.Token.DeleteSession(r.Context(), t.UserID, t.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r, types.ScopeAuthentication)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't revoke token")
+		}
 		return
 	}
+	app.refreshDenylist(r.Context())
 
 	err = app.writeJSON(w, &data.JSONResponse{
-		Envelope:   data.Envelope{"auth": token},
-		StatusCode: http.StatusCreated,
+		Envelope:   data.Envelope{"message": "Logged out successfully"},
+		StatusCode: http.StatusOK,
 	})
 	if err != nil {
-		app.serverErrorResponse(w, r, err, "Failed to serialize data")
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
 	}
 }
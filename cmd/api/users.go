@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -25,7 +26,7 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	slog.Debug("Creating a new user", "input", input)
+	slog.DebugContext(r.Context(), "Creating a new user", "input", input)
 
 	v := validator.New()
 
@@ -36,7 +37,7 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		PlainPassword: input.Password,
 	}
 
-	slog.Debug("Validating new user")
+	slog.DebugContext(r.Context(), "Validating new user")
 
 	u.Validate(v)
 	if !v.Valid() {
@@ -44,18 +45,18 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	hp, err := types.NewHashPW(input.Password)
+	hp, err := types.NewHashPW(r.Context(), input.Password)
 	if err != nil {
 		app.serverErrorResponse(w, r, err, "Failed to hash password")
 		return
 	}
 	u.HashedPassword = hp
 
-	slog.Debug("Inserting new user into database")
+	slog.DebugContext(r.Context(), "Inserting new user into database")
 
-	err = app.models.User.Insert(u)
+	err = app.models.User.Insert(r.Context(), u)
 	if err != nil {
-		slog.Debug("Got an error on user insert", "err", err)
+		slog.DebugContext(r.Context(), "Got an error on user insert", "err", err)
 		switch {
 		case errors.Is(err, types.ErrDuplicateKey):
 			// TODO: We probably don't want to use this to avoid user enumeration
@@ -66,17 +67,17 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	t, err := app.models.Token.New(u.ID, app.config.ActivationTTL, types.ScopeActivation, false)
+	t, err := app.models.Token.New(r.Context(), u.ID, app.config.ActivationTTL, types.ScopeActivation)
 	if err != nil {
-		slog.Debug("Got an error on token insert", "err", err)
+		slog.DebugContext(r.Context(), "Got an error on token insert", "err", err)
 		app.serverErrorResponse(w, r, err, "Couldn't create activation token")
 		return
 	}
 
-	err = app.models.Permission.AddForUser(u.ID, types.CompanyRead, types.CompanyWrite)
+	err = app.models.Role.AddForUser(r.Context(), app.contextGetActorID(r), u.ID, app.config.DefaultRoles...)
 	if err != nil {
-		slog.Debug("Got an error from adding user permissions", "err", err)
-		app.serverErrorResponse(w, r, err, "Couldn't grant default permissions to user")
+		slog.DebugContext(r.Context(), "Got an error from assigning user roles", "err", err)
+		app.serverErrorResponse(w, r, err, "Couldn't assign default roles to user")
 		return
 	}
 
@@ -85,11 +86,13 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		"token": t,
 	}
 
-	slog.Debug("Starting mail sender go routine")
-	close := func() error { return app.mailer.Send(u.Email, "user_welcome.tmpl", templateData) }
-	app.background(close)
+	slog.DebugContext(r.Context(), "Starting mail sender go routine")
+	close := func(ctx context.Context) error {
+		return app.mailer.Send(ctx, u.Email, "user_welcome.tmpl", templateData)
+	}
+	app.background(r.Context(), close)
 
-	slog.Debug("Serializing response")
+	slog.DebugContext(r.Context(), "Serializing response")
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/users/%d", u.ID))
@@ -106,7 +109,7 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
-	slog.Debug("Processing request for user activation")
+	slog.DebugContext(r.Context(), "Processing request for user activation")
 
 	var input struct {
 		PlainToken types.PlainToken `json:"token"`
@@ -118,17 +121,17 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	slog.Debug("Validating JSON payload")
+	slog.DebugContext(r.Context(), "Validating JSON payload")
 	v := validator.New()
 	input.PlainToken.Validate(v)
 	if !v.Valid() {
-		slog.Debug("Token validation failed", "errors", v.Errors())
+		slog.DebugContext(r.Context(), "Token validation failed", "errors", v.Errors())
 		app.invalidTokenResponse(w, r, types.ScopeActivation)
 		return
 	}
 
-	slog.Debug("Looking up token in database")
-	t, err := app.models.Token.GetOne(input.PlainToken, types.ScopeActivation)
+	slog.DebugContext(r.Context(), "Looking up token in database")
+	t, err := app.models.Token.GetOne(r.Context(), input.PlainToken, types.ScopeActivation)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -139,8 +142,8 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	slog.Debug("Activating user", "id", t.UserID)
-	id, err := app.models.User.Activate(*t)
+	slog.DebugContext(r.Context(), "Activating user", "id", t.UserID)
+	id, err := app.models.User.Activate(r.Context(), *t)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -150,11 +153,11 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
-	slog.Debug("Activated user", "id", id)
+	slog.DebugContext(r.Context(), "Activated user", "id", id)
 
-	slog.Debug("Starting token cleanup go routine")
-	close := func() error { return app.models.User.DeleteTokensForUser(id, "activation") }
-	app.background(close)
+	slog.DebugContext(r.Context(), "Starting token cleanup go routine")
+	close := func(ctx context.Context) error { return app.models.User.DeleteTokensForUser(ctx, id, "activation") }
+	app.background(r.Context(), close)
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"message": fmt.Sprintf("Activated user %d", id)},
@@ -166,15 +169,108 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	slog.DebugContext(r.Context(), "Processing request for password reset")
+
+	var input struct {
+		PlainToken    types.PlainToken `json:"token"`
+		PlainPassword types.PlainPW    `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Validating JSON payload")
+	v := validator.New()
+	input.PlainPassword.Validate(v)
+	input.PlainToken.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors())
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Looking up token in database")
+	t, err := app.models.Token.GetOne(r.Context(), input.PlainToken, types.ScopePasswordReset)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r, types.ScopePasswordReset)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't parse password reset token")
+		}
+		return
+	}
+
+	version, err := app.models.User.GetVersion(r.Context(), t.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r, types.ScopePasswordReset)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't reset password")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Hashing password")
+	hp, err := types.NewHashPW(r.Context(), input.PlainPassword)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't reset password")
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Updating password in database", "id", t.UserID)
+	_, err = app.models.User.PartialUpdate(r.Context(), t.UserID, version, &types.PartialUser{HashedPassword: &hp})
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err, "Couldn't reset password")
+		}
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Revoking reset, authentication, and refresh tokens for user", "id", t.UserID)
+	scopes := []types.TokenScope{types.ScopePasswordReset, types.ScopeAuthentication, types.ScopeRefresh}
+	for _, scope := range scopes {
+		err = app.models.Token.DeleteForUser(r.Context(), string(scope), t.UserID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err, "Couldn't revoke existing tokens")
+			return
+		}
+	}
+	app.refreshDenylist(r.Context())
+
+	slog.DebugContext(r.Context(), "Deleting API keys for user", "id", t.UserID)
+	err = app.models.ApiKey.DeleteForUser(r.Context(), t.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Couldn't delete existing API keys")
+		return
+	}
+
+	err = app.writeJSON(w, &data.JSONResponse{
+		Envelope:   data.Envelope{"message": "Your password was reset successfully"},
+		StatusCode: http.StatusOK,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err, "Failed to serialize response")
+		return
+	}
+}
+
 func (app *application) readUserHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.parseIdParam(r)
 	if err != nil {
 		app.badIdResponse(w, r, err)
 		return
 	}
-	slog.Debug("Fetching user details", "id", id)
+	slog.DebugContext(r.Context(), "Fetching user details", "id", id)
 
-	u, err := app.models.User.GetOne(id)
+	u, err := app.models.User.GetOne(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -184,7 +280,7 @@ func (app *application) readUserHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
-	slog.Debug("Retrieved user", "User", *u)
+	slog.DebugContext(r.Context(), "Retrieved user", "User", *u)
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"user": u},
@@ -196,7 +292,7 @@ func (app *application) readUserHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *application) readManyUsersHandler(w http.ResponseWriter, r *http.Request) {
-	slog.Debug("Fetching user list")
+	slog.DebugContext(r.Context(), "Fetching user list")
 
 	qs := r.URL.Query()
 	v := validator.New()
@@ -213,13 +309,13 @@ func (app *application) readManyUsersHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	slog.Debug("Retrieved filters", "filters", filters)
+	slog.DebugContext(r.Context(), "Retrieved filters", "filters", filters)
 
-	users, metadata, err := app.models.User.GetMany(filters)
+	users, metadata, err := app.models.User.GetMany(r.Context(), filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err, "Couldn't retrieve users")
 	}
-	slog.Debug("Fetched users", "metadata", metadata)
+	slog.DebugContext(r.Context(), "Fetched users", "metadata", metadata)
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		StatusCode: http.StatusOK,
@@ -240,9 +336,9 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	slog.Debug("Updating user", "id", id)
+	slog.DebugContext(r.Context(), "Updating user", "id", id)
 
-	u, err := app.models.User.GetOne(id)
+	u, err := app.models.User.GetOne(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -252,7 +348,7 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
-	slog.Debug("Retrieved user", "User", *u)
+	slog.DebugContext(r.Context(), "Retrieved user", "User", *u)
 
 	var input struct {
 		Name     string        `json:"name"`
@@ -266,12 +362,12 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	slog.Debug("Updating user with request payload", "id", id, "input", input)
+	slog.DebugContext(r.Context(), "Updating user with request payload", "id", id, "input", input)
 
 	u.Name = input.Name
 	u.Email = input.Email
 
-	slog.Debug("Validating updated company", "id", id, "company", u)
+	slog.DebugContext(r.Context(), "Validating updated company", "id", id, "company", u)
 
 	v := validator.New()
 	u.Validate(v)
@@ -282,17 +378,17 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 
 	const genericMessage = "Couldn't update user"
 
-	slog.Debug("Hashing password")
-	hp, err := types.NewHashPW(input.Password)
+	slog.DebugContext(r.Context(), "Hashing password")
+	hp, err := types.NewHashPW(r.Context(), input.Password)
 	if err != nil {
 		app.serverErrorResponse(w, r, err, genericMessage)
 		return
 	}
 	u.HashedPassword = hp
 
-	slog.Debug("Updating company in database")
+	slog.DebugContext(r.Context(), "Updating company in database")
 
-	err = app.models.User.Update(u)
+	err = app.models.User.Update(r.Context(), u)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrEditConflict):
@@ -305,7 +401,7 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	slog.Debug("Serializing response")
+	slog.DebugContext(r.Context(), "Serializing response")
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"company": u},
@@ -324,9 +420,9 @@ func (app *application) updatePartialUserHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	slog.Debug("Partially updating user", "id", id)
+	slog.DebugContext(r.Context(), "Partially updating user", "id", id)
 
-	version, err := app.models.User.GetVersion(id)
+	version, err := app.models.User.GetVersion(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -336,7 +432,7 @@ func (app *application) updatePartialUserHandler(w http.ResponseWriter, r *http.
 		}
 		return
 	}
-	slog.Debug("Retrieved version", "Version", version)
+	slog.DebugContext(r.Context(), "Retrieved version", "Version", version)
 
 	pu := types.PartialUser{}
 
@@ -345,9 +441,9 @@ func (app *application) updatePartialUserHandler(w http.ResponseWriter, r *http.
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	slog.Debug("Updating user with request payload", "id", id, "input", pu)
+	slog.DebugContext(r.Context(), "Updating user with request payload", "id", id, "input", pu)
 
-	slog.Debug("Validating partial user", "id", id, "partial_user", pu)
+	slog.DebugContext(r.Context(), "Validating partial user", "id", id, "partial_user", pu)
 	v := validator.New()
 	pu.Validate(v)
 	if !v.Valid() {
@@ -356,8 +452,8 @@ func (app *application) updatePartialUserHandler(w http.ResponseWriter, r *http.
 	}
 
 	if pu.PlainPassword != nil {
-		slog.Debug("Hashing password")
-		hp, err := types.NewHashPW(*pu.PlainPassword)
+		slog.DebugContext(r.Context(), "Hashing password")
+		hp, err := types.NewHashPW(r.Context(), *pu.PlainPassword)
 		if err != nil {
 			app.serverErrorResponse(w, r, err, "Couldn't update user")
 			return
@@ -365,8 +461,8 @@ func (app *application) updatePartialUserHandler(w http.ResponseWriter, r *http.
 		pu.HashedPassword = &hp
 	}
 
-	slog.Debug("Updating user in database")
-	c, err := app.models.User.PartialUpdate(id, version, &pu)
+	slog.DebugContext(r.Context(), "Updating user in database")
+	c, err := app.models.User.PartialUpdate(r.Context(), id, version, &pu)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrEditConflict):
@@ -377,7 +473,7 @@ func (app *application) updatePartialUserHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	slog.Debug("Serializing response")
+	slog.DebugContext(r.Context(), "Serializing response")
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"user": c},
@@ -395,9 +491,9 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		app.badIdResponse(w, r, err)
 		return
 	}
-	slog.Debug("Deleting user", "id", id)
+	slog.DebugContext(r.Context(), "Deleting user", "id", id)
 
-	err = app.models.User.Delete(id)
+	err = app.models.User.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, types.ErrRecordNotFound):
@@ -407,7 +503,8 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
-	slog.Debug("Deleted user", "id", id)
+	slog.DebugContext(r.Context(), "Deleted user", "id", id)
+	app.refreshDenylist(r.Context())
 
 	err = app.writeJSON(w, &data.JSONResponse{
 		Envelope:   data.Envelope{"message": "User deleted successfully"},
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+// scopes lists every token scope so that all of a user's tokens can be revoked at once.
+var scopes = []types.TokenScope{
+	types.ScopeActivation,
+	types.ScopeAuthentication,
+	types.ScopePasswordReset,
+	types.ScopeRefresh,
+	types.ScopeMFAPending,
+}
+
+// ctl runs the commands. Changes are made as the system user since the operator isn't a user of the API.
+type ctl struct {
+	cfg    data.Config
+	models data.Models
+	out    output
+}
+
+func (c *ctl) run(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("a command needs a group and an action, like \"users create\"")
+	}
+
+	command := args[0] + " " + args[1]
+	args = args[2:]
+	switch command {
+	case "users create":
+		return c.createUser(ctx, args)
+	case "users activate":
+		return c.activateUser(ctx, args)
+	case "users reset-password":
+		return c.resetPassword(ctx, args)
+	case "perms list":
+		return c.listPerms(ctx, args)
+	case "perms grant":
+		return c.grantPerms(ctx, args)
+	case "perms revoke":
+		return c.revokePerms(ctx, args)
+	case "tokens list":
+		return c.listTokens(ctx, args)
+	case "tokens revoke":
+		return c.revokeToken(ctx, args)
+	case "tokens revoke-all":
+		return c.revokeAllTokens(ctx, args)
+	case "tokens purge":
+		return c.purgeTokens(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// parse reads the flags of a command and checks how many arguments follow them. A negative count means at least
+// that many.
+func parse(fs *flag.FlagSet, args []string, count int) ([]string, error) {
+	fs.SetOutput(os.Stderr)
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := fs.Args()
+	switch {
+	case count >= 0 && len(rest) != count:
+		return nil, fmt.Errorf("%s takes %d arguments but got %d", fs.Name(), count, len(rest))
+	case count < 0 && len(rest) < -count:
+		return nil, fmt.Errorf("%s takes at least %d arguments but got %d", fs.Name(), -count, len(rest))
+	}
+	return rest, nil
+}
+
+func (c *ctl) findUser(ctx context.Context, email string) (*types.User, error) {
+	u, err := c.models.User.GetByEmail(ctx, types.Email(email))
+	if errors.Is(err, types.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no user has the email %s", email)
+	}
+	return u, err
+}
+
+// password checks the provided password or generates one if it's empty. Generated passwords have to be shown to the
+// operator, so the second return value is the password to print.
+func password(provided string) (types.PlainPW, types.PlainPW, error) {
+	if provided == "" {
+		pp := types.PlainPW(rand.Text())
+		return pp, pp, nil
+	}
+
+	pp := types.PlainPW(provided)
+	v := validator.New()
+	pp.Validate(v)
+	if !v.Valid() {
+		return "", "", fmt.Errorf("password is invalid: %v", v.Errors())
+	}
+	return pp, "", nil
+}
+
+// revokeTokens deletes the user's tokens in each of the scopes.
+func (c *ctl) revokeTokens(ctx context.Context, userID int64, scopes ...types.TokenScope) error {
+	for _, scope := range scopes {
+		err := c.models.Token.DeleteForUser(ctx, string(scope), userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ctl) printUser(u *types.User, generated types.PlainPW) error {
+	v := struct {
+		*types.User
+		Password types.PlainPW `json:"password,omitzero"`
+	}{u, generated}
+
+	headers := []string{"ID", "NAME", "EMAIL", "ACTIVATED", "ADMIN"}
+	row := []string{
+		strconv.FormatInt(u.ID, 10),
+		u.Name,
+		string(u.Email),
+		strconv.FormatBool(u.Activated),
+		strconv.FormatBool(u.IsAdmin),
+	}
+	if generated != "" {
+		headers = append(headers, "PASSWORD")
+		row = append(row, string(generated))
+	}
+	return c.out.table(v, headers, [][]string{row})
+}
+
+// createUser adds a user with the default roles, just like registering through the API, but doesn't send the
+// welcome mail. Users created with -activated can log in right away.
+func (c *ctl) createUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+	name := fs.String("name", "", "Name of the user")
+	email := fs.String("email", "", "Email of the user")
+	pw := fs.String("password", "", "Password of the user (generated if empty)")
+	activated := fs.Bool("activated", false, "Activate the user without an activation token")
+	_, err := parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	pp, generated, err := password(*pw)
+	if err != nil {
+		return err
+	}
+
+	u := &types.User{
+		Name:          *name,
+		Email:         types.Email(*email),
+		Activated:     *activated,
+		PlainPassword: pp,
+	}
+
+	v := validator.New()
+	u.Validate(v)
+	if !v.Valid() {
+		return fmt.Errorf("user is invalid: %v", v.Errors())
+	}
+
+	u.HashedPassword, err = types.NewHashPW(ctx, pp)
+	if err != nil {
+		return err
+	}
+
+	err = c.models.User.Insert(ctx, u)
+	if errors.Is(err, types.ErrDuplicateKey) {
+		return fmt.Errorf("a user with the email %s already exists", u.Email)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = c.models.Role.AddForUser(ctx, types.SystemUserID, u.ID, c.cfg.DefaultRoles...)
+	if err != nil {
+		return err
+	}
+
+	return c.printUser(u, generated)
+}
+
+func (c *ctl) activateUser(ctx context.Context, args []string) error {
+	rest, err := parse(flag.NewFlagSet("users activate", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	u, err := c.findUser(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+
+	// Activate only needs to know whose token was presented.
+	_, err = c.models.User.Activate(ctx, types.Token{UserID: u.ID})
+	if err != nil {
+		return err
+	}
+	u.Activated = true
+
+	err = c.revokeTokens(ctx, u.ID, types.ScopeActivation)
+	if err != nil {
+		return err
+	}
+
+	return c.printUser(u, "")
+}
+
+// resetPassword sets a new password and signs the user out everywhere, just like a reset through the API.
+func (c *ctl) resetPassword(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users reset-password", flag.ContinueOnError)
+	pw := fs.String("password", "", "New password of the user (generated if empty)")
+	rest, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	pp, generated, err := password(*pw)
+	if err != nil {
+		return err
+	}
+
+	u, err := c.findUser(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+
+	hp, err := types.NewHashPW(ctx, pp)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.models.User.PartialUpdate(ctx, u.ID, u.Version, &types.PartialUser{HashedPassword: &hp})
+	if err != nil {
+		return err
+	}
+
+	err = c.revokeTokens(ctx, u.ID, types.ScopePasswordReset, types.ScopeAuthentication, types.ScopeRefresh)
+	if err != nil {
+		return err
+	}
+
+	err = c.models.ApiKey.DeleteForUser(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.printUser(u, generated)
+}
+
+func (c *ctl) printPerms(ctx context.Context, u *types.User) error {
+	ps, err := c.models.Permission.GetForUser(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	perms := slices.Sorted(ps.Items())
+	rows := make([][]string, 0, len(perms))
+	for _, pc := range perms {
+		rows = append(rows, []string{string(pc)})
+	}
+	return c.out.table(perms, []string{"PERMISSION"}, rows)
+}
+
+// permArgs finds the user and checks the permission codes that follow the email.
+func (c *ctl) permArgs(ctx context.Context, name string, args []string) (*types.User, []types.PermCode, error) {
+	rest, err := parse(flag.NewFlagSet(name, flag.ContinueOnError), args, -2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	perms := make([]types.PermCode, 0, len(rest)-1)
+	for _, code := range rest[1:] {
+		perms = append(perms, types.PermCode(code))
+	}
+
+	known, err := c.models.Permission.GetAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v := validator.New()
+	types.ValidatePermCodes(v, perms, types.NewPermissionSet(known...))
+	if !v.Valid() {
+		return nil, nil, fmt.Errorf("permissions are invalid: %v", v.Errors())
+	}
+
+	u, err := c.findUser(ctx, rest[0])
+	return u, perms, err
+}
+
+func (c *ctl) listPerms(ctx context.Context, args []string) error {
+	rest, err := parse(flag.NewFlagSet("perms list", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	u, err := c.findUser(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+
+	return c.printPerms(ctx, u)
+}
+
+func (c *ctl) grantPerms(ctx context.Context, args []string) error {
+	u, perms, err := c.permArgs(ctx, "perms grant", args)
+	if err != nil {
+		return err
+	}
+
+	err = c.models.Permission.AddForUser(ctx, types.SystemUserID, u.ID, perms...)
+	if err != nil {
+		return err
+	}
+
+	return c.printPerms(ctx, u)
+}
+
+// revokePerms only removes permissions granted directly to the user. Permissions that come from a role stay. When the
+// API signs its access tokens, the user is also logged out so that the revoked permissions stop working right away.
+func (c *ctl) revokePerms(ctx context.Context, args []string) error {
+	u, perms, err := c.permArgs(ctx, "perms revoke", args)
+	if err != nil {
+		return err
+	}
+
+	err = c.models.Permission.RevokeForUser(ctx, types.SystemUserID, u.ID, perms...)
+	if err != nil {
+		return err
+	}
+
+	if c.cfg.TokenMode == types.TokenModeJWT {
+		err = c.revokeTokens(ctx, u.ID, types.ScopeAuthentication, types.ScopeRefresh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.printPerms(ctx, u)
+}
+
+func (c *ctl) listTokens(ctx context.Context, args []string) error {
+	rest, err := parse(flag.NewFlagSet("tokens list", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	u, err := c.findUser(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+
+	tokens, err := c.models.Token.GetAllForUser(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(tokens))
+	for _, t := range tokens {
+		rows = append(rows, []string{
+			strconv.FormatInt(t.ID, 10),
+			string(t.Scope),
+			formatTime(&t.CreatedAt),
+			formatTime(t.LastUsedAt),
+			formatTime(&t.ExpiresAt),
+			formatTime(t.RotatedAt),
+			t.IP,
+			t.UserAgent,
+		})
+	}
+	headers := []string{"ID", "SCOPE", "CREATED", "LAST USED", "EXPIRES", "ROTATED", "IP", "USER AGENT"}
+	return c.out.table(tokens, headers, rows)
+}
+
+// revokeToken deletes one token by its ID. The rest of its family goes with it so that a refresh token can't bring
+// the session back.
+func (c *ctl) revokeToken(ctx context.Context, args []string) error {
+	rest, err := parse(flag.NewFlagSet("tokens revoke", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(rest[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("token ID must be an integer: %q", rest[0])
+	}
+
+	err = c.models.Token.Delete(ctx, id)
+	if errors.Is(err, types.ErrRecordNotFound) {
+		return fmt.Errorf("no token has the ID %d", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.out.message(map[string]any{"revoked": id}, fmt.Sprintf("Revoked token %d", id))
+}
+
+func (c *ctl) revokeAllTokens(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tokens revoke-all", flag.ContinueOnError)
+	scope := fs.String("scope", "", "Only revoke tokens with this scope")
+	rest, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	revoke := scopes
+	if *scope != "" {
+		if !slices.Contains(scopes, types.TokenScope(*scope)) {
+			return fmt.Errorf("unknown token scope %q", *scope)
+		}
+		revoke = []types.TokenScope{types.TokenScope(*scope)}
+	}
+
+	u, err := c.findUser(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+
+	err = c.revokeTokens(ctx, u.ID, revoke...)
+	if err != nil {
+		return err
+	}
+
+	return c.out.message(
+		map[string]any{"user_id": u.ID, "scopes": revoke},
+		fmt.Sprintf("Revoked the %v tokens of user %d", revoke, u.ID),
+	)
+}
+
+func (c *ctl) purgeTokens(ctx context.Context, args []string) error {
+	_, err := parse(flag.NewFlagSet("tokens purge", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+
+	count, err := c.models.Token.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.out.message(map[string]any{"purged": count}, fmt.Sprintf("Purged %d expired tokens", count))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// noRoles stands in for the role store. Roles are accepted but ignored.
+type noRoles struct {
+	data.RoleStore
+}
+
+func (noRoles) AddForUser(_ context.Context, _ int64, _ int64, _ ...string) error {
+	return nil
+}
+
+// deletedKeys stands in for the API key store. It only records whose keys were deleted.
+type deletedKeys struct {
+	data.ApiKeyStore
+	users map[int64]bool
+}
+
+func (s deletedKeys) DeleteForUser(_ context.Context, userID int64) error {
+	s.users[userID] = true
+	return nil
+}
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	models := data.NewMemoryModels(data.NewMemoryStore())
+	models.Role = noRoles{}
+	keys := deletedKeys{users: make(map[int64]bool)}
+	models.ApiKey = keys
+
+	alice := &types.User{Name: "Alice", Email: "alice@example.com", HashedPassword: []byte("x")}
+	err := models.User.Insert(ctx, alice)
+	if err != nil {
+		t.Fatalf("Couldn't insert user: %v", err)
+	}
+
+	family := types.NewTokenFamily()
+	access := types.GenerateToken(alice.ID, time.Hour, types.ScopeAuthentication)
+	refresh := types.GenerateToken(alice.ID, time.Hour, types.ScopeRefresh)
+	access.Family = family
+	refresh.Family = family
+	tokens := []*types.Token{
+		access,
+		refresh,
+		types.GenerateToken(alice.ID, -time.Hour, types.ScopePasswordReset),
+		types.GenerateToken(alice.ID, time.Hour, types.ScopeActivation),
+	}
+	err = models.Token.InsertFamily(ctx, tokens...)
+	if err != nil {
+		t.Fatalf("Couldn't insert tokens: %v", err)
+	}
+
+	steps := []struct {
+		args    string
+		json    bool
+		wantErr string
+		want    []string
+		notWant []string
+	}{
+		{
+			args: "users create -name Carol -email carol@example.com -password correct-horse",
+			want: []string{"ID  NAME   EMAIL              ACTIVATED  ADMIN", "Carol  carol@example.com  false"},
+		},
+		{
+			args:    "users create -name Carol -email CAROL@example.com -password correct-horse",
+			wantErr: "a user with the email CAROL@example.com already exists",
+		},
+		{
+			args:    "users create -name Dave -email dave -password correct-horse",
+			wantErr: "user is invalid",
+		},
+		{
+			args:    "users create -name Dave -email dave@example.com -password short",
+			wantErr: "password is invalid",
+		},
+		{
+			args: "users create -name Dave -email dave@example.com -activated",
+			json: true,
+			want: []string{`"email": "dave@example.com"`, `"activated": true`, `"password": "`},
+		},
+		{args: "users activate alice@example.com", want: []string{"alice@example.com  true"}},
+		{args: "users activate nobody@example.com", wantErr: "no user has the email nobody@example.com"},
+		{
+			args: "perms grant alice@example.com companies:read companies:write",
+			json: true,
+			want: []string{"[\n\t\"companies:read\",\n\t\"companies:write\"\n]"},
+		},
+		{args: "perms grant alice@example.com bogus:code", wantErr: "permissions are invalid"},
+		{args: "perms grant alice@example.com", wantErr: "perms grant takes at least 2 arguments but got 1"},
+		{
+			args:    "perms revoke alice@example.com companies:write",
+			want:    []string{"PERMISSION\ncompanies:read\n"},
+			notWant: []string{"companies:write"},
+		},
+		{
+			args: "tokens list alice@example.com",
+			want: []string{"authentication", "refresh", "password-reset"},
+			// Activating the user revoked the activation token.
+			notWant: []string{"activation"},
+		},
+		{args: "tokens purge", want: []string{"Purged 1 expired tokens"}},
+		{args: "tokens list alice@example.com", notWant: []string{"password-reset"}},
+		{args: "tokens revoke 1", json: true, want: []string{`"revoked": 1`}},
+		{args: "tokens revoke 1", wantErr: "no token has the ID 1"},
+		{args: "tokens revoke one", wantErr: "token ID must be an integer"},
+		{args: "tokens list alice@example.com", json: true, want: []string{"[]"}},
+		{args: "tokens revoke-all -scope bogus alice@example.com", wantErr: `unknown token scope "bogus"`},
+		{args: "tokens revoke-all alice@example.com", want: []string{"Revoked the [activation authentication"}},
+		{args: "users reset-password -password correct-horse alice@example.com", want: []string{"alice@example.com"}},
+		{args: "users delete alice@example.com", wantErr: `unknown command "users delete"`},
+	}
+	for _, s := range steps {
+		var buf bytes.Buffer
+		c := &ctl{models: models, out: output{w: &buf, json: s.json}}
+		err := c.run(ctx, strings.Fields(s.args))
+
+		switch {
+		case s.wantErr == "" && err != nil:
+			t.Errorf("%s: returned an error: %v", s.args, err)
+		case s.wantErr != "" && (err == nil || !strings.Contains(err.Error(), s.wantErr)):
+			t.Errorf("%s: returned %v; want an error containing %q", s.args, err, s.wantErr)
+		}
+
+		got := buf.String()
+		for _, want := range s.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: output doesn't contain %q:\n%s", s.args, want, got)
+			}
+		}
+		for _, notWant := range s.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("%s: output contains %q:\n%s", s.args, notWant, got)
+			}
+		}
+	}
+
+	_, err = models.User.GetForLogin(ctx, types.NewLogin("alice@example.com", "correct-horse"))
+	if err != nil {
+		t.Errorf("Couldn't log in with the reset password: %v", err)
+	}
+	if !keys.users[alice.ID] {
+		t.Errorf("The API keys of the user weren't deleted when their password was reset")
+	}
+}
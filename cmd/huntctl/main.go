@@ -0,0 +1,107 @@
+// Command huntctl lets an operator manage users, permissions and tokens directly through the models, without going
+// through the API. It reads the same environment (and optional .env file) as the API to find the database.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+)
+
+const usage = `Usage: huntctl [-output table|json] <command> [arguments]
+
+Commands:
+  users create -name NAME -email EMAIL [-password PASSWORD] [-activated]
+  users activate EMAIL
+  users reset-password [-password PASSWORD] EMAIL
+  perms list EMAIL
+  perms grant EMAIL CODE...
+  perms revoke EMAIL CODE...
+  tokens list EMAIL
+  tokens revoke ID
+  tokens revoke-all [-scope SCOPE] EMAIL
+  tokens purge
+
+A password is generated and printed when none is provided. Flags go before the other arguments.
+
+Flags:
+`
+
+func main() {
+	format := flag.String("output", formatTable, "Output format: table or json")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := run(*format, flag.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "huntctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(format string, args []string) error {
+	out, err := newOutput(os.Stdout, format)
+	if err != nil {
+		return err
+	}
+
+	// If the .env file is not found, we don't care. It's optional.
+	_ = godotenv.Load()
+	var cfg data.Config
+	err = env.Parse(&cfg)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := &ctl{
+		cfg:    cfg,
+		models: data.NewModels(db, data.NewModelConfig(cfg)),
+		out:    out,
+	}
+	return c.run(context.Background(), args)
+}
+
+func openDB(cfg data.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const formatTable = "table"
+const formatJSON = "json"
+
+// output writes the result of a command either as a table for people or as JSON for scripts.
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+func newOutput(w io.Writer, format string) (output, error) {
+	switch format {
+	case formatTable:
+		return output{w: w}, nil
+	case formatJSON:
+		return output{w: w, json: true}, nil
+	default:
+		return output{}, fmt.Errorf("unknown output format %q: must be table or json", format)
+	}
+}
+
+// table writes v as JSON or the headers and rows as an aligned table.
+func (o output) table(v any, headers []string, rows [][]string) error {
+	if o.json {
+		return o.writeJSON(v)
+	}
+
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// message writes v as JSON or the text as a single line.
+func (o output) message(v any, text string) error {
+	if o.json {
+		return o.writeJSON(v)
+	}
+
+	_, err := fmt.Fprintln(o.w, text)
+	return err
+}
+
+func (o output) writeJSON(v any) error {
+	js, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(o.w, string(js))
+	return err
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -21,10 +21,10 @@ users = [
 ]
 
 companies = [
-    dict(name="Close", url="https://close.com", tech_stack=["Python", "PostgreSQL", "Kubernetes"]),
-    dict(name="Clever", url="https://clever.com", tech_stack=["Go", "Kubernetes"]),
-    dict(name="iSpotTV", url="https://ispot.com", tech_stack=["Java", "Mysql", "Kubernetes"]),
-    dict(name="Canonical", url="https://canonical.org", tech_stack=["Python", "Go", "Kubernetes"]),
+    dict(name="Close", url="https://close.com", tech_stack=["Python", "PostgreSQL", "Kubernetes"], visibility="public"),
+    dict(name="Clever", url="https://clever.com", tech_stack=["Go", "Kubernetes"], visibility="public"),
+    dict(name="iSpotTV", url="https://ispot.com", tech_stack=["Java", "Mysql", "Kubernetes"], visibility="public"),
+    dict(name="Canonical", url="https://canonical.org", tech_stack=["Python", "Go", "Kubernetes"], visibility="public"),
 ]
 
 def delete_all_companies(client: httpx.Client):
@@ -52,6 +52,8 @@ def delete_all_users(client: httpx.Client):
     r.raise_for_status()
 
     for user in r.json()["users"]:
+        if user["is_admin"]:
+            continue
         logger.info(f"Deleting: {user['name']}")
         r = client.delete(f"/users/{user['id']}")
         r.raise_for_status()
@@ -71,7 +73,7 @@ def auth_me(token: str) -> dict[str, dict[str, str]]:
 
 def main():
     logger.info("Getting admin auth token")
-    r = httpx.post("http://localhost:4000/v1/login", json=dict(email="admin@the-hunt.dev", password="admin"))
+    r = httpx.post("http://localhost:4000/v1/login", json=dict(email="admin@the-hunt.dev", password="the-hunt-admin"))
     r.raise_for_status()
     admin_token = r.json()["auth"]["token"]
     logger.info(f"Got admin token: {admin_token}")
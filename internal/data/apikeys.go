@@ -0,0 +1,221 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type ApiKeyModel struct {
+	DB  *sql.DB
+	CFG ModelConfig
+}
+
+func permCodes(codes []string) []types.PermCode {
+	perms := make([]types.PermCode, 0, len(codes))
+	for _, code := range codes {
+		perms = append(perms, types.PermCode(code))
+	}
+	return perms
+}
+
+// Insert adds the key and its permissions in a single transaction.
+func (m ApiKeyModel) Insert(ctx context.Context, k *types.ApiKey) error {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		insert into api_keys (user_id, name, hash, expires_at)
+		values ($1, $2, $3, $4)
+		returning id, created_at
+	`
+	args := []any{
+		k.UserID,
+		k.Name,
+		k.Hash,
+		k.ExpiresAt,
+	}
+
+	err = types.MapError(
+		tx.QueryRowContext(ctx, query, args...).Scan(&k.ID, &k.CreatedAt),
+		types.ErrorMap{".*duplicate key.*": types.ErrDuplicateKey},
+	)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		insert into api_key_permissions (api_key_id, permission_id)
+		select $1, permissions.id
+		from permissions
+		where permissions.code = any($2)
+	`
+	_, err = tx.ExecContext(ctx, query, k.ID, pq.Array(k.Permissions))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetOne finds an unexpired key by its plaintext value.
+func (m ApiKeyModel) GetOne(ctx context.Context, pt types.PlainToken) (*types.ApiKey, error) {
+	query := `
+		select
+			api_keys.id,
+			api_keys.created_at,
+			api_keys.user_id,
+			api_keys.name,
+			api_keys.expires_at,
+			api_keys.last_used_at,
+			array_remove(array_agg(permissions.code), null)
+		from api_keys
+		left join api_key_permissions on api_key_permissions.api_key_id = api_keys.id
+		left join permissions on permissions.id = api_key_permissions.permission_id
+		where api_keys.hash = $1
+		and (api_keys.expires_at is null or api_keys.expires_at > $2)
+		group by api_keys.id
+	`
+	args := []any{
+		types.Hash(string(pt)),
+		time.Now(),
+	}
+
+	var k types.ApiKey
+	var perms []string
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	err := types.MapError(
+		m.DB.QueryRowContext(ctx, query, args...).Scan(
+			&k.ID,
+			&k.CreatedAt,
+			&k.UserID,
+			&k.Name,
+			&k.ExpiresAt,
+			&k.LastUsedAt,
+			pq.Array(&perms),
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+	k.Permissions = permCodes(perms)
+	return &k, err
+}
+
+func (m ApiKeyModel) GetForUser(ctx context.Context, userID int64) ([]*types.ApiKey, error) {
+	query := `
+		select
+			api_keys.id,
+			api_keys.created_at,
+			api_keys.user_id,
+			api_keys.name,
+			api_keys.expires_at,
+			api_keys.last_used_at,
+			array_remove(array_agg(permissions.code order by permissions.code), null)
+		from api_keys
+		left join api_key_permissions on api_key_permissions.api_key_id = api_keys.id
+		left join permissions on permissions.id = api_key_permissions.permission_id
+		where api_keys.user_id = $1
+		group by api_keys.id
+		order by api_keys.created_at, api_keys.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*types.ApiKey, 0, 4)
+	for rows.Next() {
+		var k types.ApiKey
+		var perms []string
+		err := rows.Scan(
+			&k.ID,
+			&k.CreatedAt,
+			&k.UserID,
+			&k.Name,
+			&k.ExpiresAt,
+			&k.LastUsedAt,
+			pq.Array(&perms),
+		)
+		if err != nil {
+			return nil, err
+		}
+		k.Permissions = permCodes(perms)
+		keys = append(keys, &k)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Touch records that the key was just used to authenticate a request.
+func (m ApiKeyModel) Touch(ctx context.Context, id int64) error {
+	query := `
+		update api_keys
+		set last_used_at = now()
+		where id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (m ApiKeyModel) Delete(ctx context.Context, userID int64, id int64) error {
+	query := `
+		delete from api_keys
+		where id = $1
+		and user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteForUser removes every API key of the user. Keys are credentials in their own right, so they go along with the
+// password when somebody else might have learned it.
+func (m ApiKeyModel) DeleteForUser(ctx context.Context, userID int64) error {
+	query := `
+		delete from api_keys
+		where user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
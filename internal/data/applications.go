@@ -0,0 +1,342 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type ApplicationModel struct {
+	DB  *sql.DB
+	CFG ModelConfig
+}
+
+var ApplicationSearchFields = NewSearchFields("status", "notes")
+var ApplicationSortFields = NewSortFields("id", "created_at", "updated_at", "status")
+
+// Insert adds the application and records its starting status as the first history row. The posting must belong to
+// the tenant. Otherwise, ErrRecordNotFound is returned.
+func (m ApplicationModel) Insert(ctx context.Context, t types.Tenant, application *types.Application) error {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		insert into applications (owner_id, posting_id, status, notes)
+		select $1::bigint, postings.id, $3::text, $4::text
+		from postings
+		where postings.id = $2
+		and (postings.owner_id = $5 or $6)
+		returning id, created_at, updated_at, coalesce(owner_id, 0), version
+	`
+	args := []any{
+		ownerArg(t),
+		application.PostingID,
+		application.Status,
+		application.Notes,
+		t.UserID,
+		t.All,
+	}
+
+	err = types.MapError(
+		tx.QueryRowContext(ctx, query, args...).Scan(
+			&application.ID,
+			&application.CreatedAt,
+			&application.UpdatedAt,
+			&application.OwnerID,
+			&application.Version,
+		),
+		types.ErrorMap{
+			sql.ErrNoRows:       types.ErrRecordNotFound,
+			".*duplicate key.*": types.ErrDuplicateKey,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		insert into application_transitions (application_id, to_status)
+		values ($1, $2)
+	`
+	_, err = tx.ExecContext(ctx, query, application.ID, application.Status)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ApplicationModel) GetOne(ctx context.Context, t types.Tenant, id int64) (*types.Application, error) {
+	query := `
+		select id, created_at, updated_at, coalesce(owner_id, 0), posting_id, status, notes, version
+		from applications
+		where id = $1
+		and (owner_id = $2 or $3)
+	`
+	var a types.Application
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return &a, types.MapError(
+		m.DB.QueryRowContext(ctx, query, id, t.UserID, t.All).Scan(
+			&a.ID,
+			&a.CreatedAt,
+			&a.UpdatedAt,
+			&a.OwnerID,
+			&a.PostingID,
+			&a.Status,
+			&a.Notes,
+			&a.Version,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+func (m ApplicationModel) GetMany(
+	ctx context.Context,
+	t types.Tenant,
+	f Filters,
+) ([]*types.Application, *ListMetadata, error) {
+	args := []any{}
+	query_parts := []string{`
+		select
+			count(*) over (),
+			id,
+			created_at,
+			updated_at,
+			coalesce(owner_id, 0),
+			posting_id,
+			status,
+			notes,
+			version
+		from applications
+	`}
+
+	where_parts := []string{}
+
+	if !t.All {
+		args = append(args, t.UserID)
+		where_parts = append(where_parts, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+
+	if f.Search != nil {
+		for k, v := range *f.Search {
+			args = append(args, v)
+			where_parts = append(where_parts, fmt.Sprintf("%s ~* $%d", k, len(args)))
+		}
+	}
+
+	if len(where_parts) > 0 {
+		query_parts = append(query_parts, "where", strings.Join(where_parts, " and "))
+	}
+
+	sort_parts := []string{}
+
+	if f.Sort != nil {
+		for k, v := range f.Sort.FromOldest() {
+			sort_parts = append(sort_parts, fmt.Sprintf("%s %s", k, v))
+		}
+	}
+
+	if len(sort_parts) > 0 {
+		query_parts = append(query_parts, "order by", strings.Join(sort_parts, ", "))
+	}
+
+	if f.Page != nil && f.PageSize != nil {
+		args = append(args, *f.PageSize, (*f.Page-1)**f.PageSize)
+		query_parts = append(query_parts, fmt.Sprintf("limit $%d offset $%d", len(args)-1, len(args)))
+	}
+
+	query := strings.Join(query_parts, " ")
+
+	slog.Debug("Assembled GetMany query", "query", query, "args", args)
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var recordCount int
+	applications := make([]*types.Application, 0, 10)
+	for rows.Next() {
+		var a types.Application
+		err := rows.Scan(
+			&recordCount,
+			&a.ID,
+			&a.CreatedAt,
+			&a.UpdatedAt,
+			&a.OwnerID,
+			&a.PostingID,
+			&a.Status,
+			&a.Notes,
+			&a.Version,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		applications = append(applications, &a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metadata := NewListMetadata(f, recordCount)
+	return applications, &metadata, nil
+}
+
+// Transition moves the application to the transition's ToStatus and records the move in the history table. The
+// update is guarded by the application's version so that two concurrent moves can't both succeed.
+func (m ApplicationModel) Transition(
+	ctx context.Context,
+	t types.Tenant,
+	application *types.Application,
+	transition *types.ApplicationTransition,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		update applications
+		set status = $1, updated_at = $2, version = version + 1
+		where id = $3 and version = $4
+		and (owner_id = $5 or $6)
+		returning updated_at, version
+	`
+	args := []any{
+		transition.ToStatus,
+		time.Now(),
+		application.ID,
+		application.Version,
+		t.UserID,
+		t.All,
+	}
+
+	err = types.MapError(
+		tx.QueryRowContext(ctx, query, args...).Scan(&application.UpdatedAt, &application.Version),
+		types.ErrorMap{sql.ErrNoRows: types.ErrEditConflict},
+	)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		insert into application_transitions (application_id, from_status, to_status, note)
+		values ($1, $2, $3, $4)
+		returning id, created_at
+	`
+	args = []any{
+		application.ID,
+		transition.FromStatus,
+		transition.ToStatus,
+		transition.Note,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&transition.ID, &transition.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	transition.ApplicationID = application.ID
+	application.Status = transition.ToStatus
+
+	return tx.Commit()
+}
+
+func (m ApplicationModel) GetTransitions(
+	ctx context.Context,
+	t types.Tenant,
+	id int64,
+) ([]*types.ApplicationTransition, error) {
+	query := `
+		select
+			application_transitions.id,
+			application_transitions.created_at,
+			application_transitions.application_id,
+			application_transitions.from_status,
+			application_transitions.to_status,
+			application_transitions.note
+		from application_transitions
+		join applications on applications.id = application_transitions.application_id
+		where applications.id = $1
+		and (applications.owner_id = $2 or $3)
+		order by application_transitions.created_at, application_transitions.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, t.UserID, t.All)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := make([]*types.ApplicationTransition, 0, 8)
+	for rows.Next() {
+		var at types.ApplicationTransition
+		err := rows.Scan(
+			&at.ID,
+			&at.CreatedAt,
+			&at.ApplicationID,
+			&at.FromStatus,
+			&at.ToStatus,
+			&at.Note,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, &at)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+func (m ApplicationModel) Delete(ctx context.Context, t types.Tenant, id int64) error {
+	query := `
+		delete from applications
+		where id = $1
+		and (owner_id = $2 or $3)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, t.UserID, t.All)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"log/slog"
 	"sync"
 	"time"
@@ -28,11 +29,23 @@ func NewClientMap(cfg Config) *ClientMap {
 		LimitRPS:        cfg.LimitRPS,
 		LimitBurst:      cfg.LimitBurst,
 		CleanupInterval: cfg.ClientCleanupInterval,
-		CleanupTimeout:  cfg.ClientCleanupInterval,
+		CleanupTimeout:  cfg.ClientCleanupTimeout,
 		Mutex:           &sync.Mutex{},
 	}
 }
 
+// NewPolicyClientMap builds a ClientMap that enforces the policy instead of the default limits.
+func NewPolicyClientMap(cfg Config, p LimitPolicy) *ClientMap {
+	cl := NewClientMap(cfg)
+	cl.LimitRPS = p.Limit
+	cl.LimitBurst = p.Burst
+
+	// Don't forget a client until its bucket would have refilled anyway. Otherwise, waiting out the cleanup would
+	// reset the limit.
+	cl.CleanupTimeout = max(cl.CleanupTimeout, p.RefillTime())
+	return cl
+}
+
 func (cl ClientMap) GetLimiter(ip string) *rate.Limiter {
 	if _, ok := cl.ClientMap[ip]; !ok {
 		cl.ClientMap[ip] = &Client{
@@ -54,11 +67,27 @@ func (cl ClientMap) Cleanup() {
 	}
 }
 
-func (cl ClientMap) IsIpAllowed(ip string) bool {
+// Allow never fails. It returns an error only to satisfy RateLimiter.
+func (cl ClientMap) Allow(_ context.Context, key string) (*LimitResult, error) {
 	cl.Mutex.Lock()
 	defer cl.Mutex.Unlock()
-	limiter := cl.GetLimiter(ip)
-	return limiter.Allow()
+	limiter := cl.GetLimiter(key)
+
+	now := time.Now()
+	res := &LimitResult{Limit: cl.LimitBurst}
+	if limiter.AllowN(now, 1) {
+		res.Allowed = true
+	} else {
+		// Reserving and immediately cancelling is the only way to ask the limiter when a token will be available.
+		rsv := limiter.ReserveN(now, 1)
+		res.RetryAfter = rsv.DelayFrom(now)
+		rsv.CancelAt(now)
+	}
+
+	tokens := limiter.TokensAt(now)
+	res.Remaining = max(int(tokens), 0)
+	res.ResetAfter = time.Duration((float64(cl.LimitBurst) - tokens) / float64(cl.LimitRPS) * float64(time.Second))
+	return res, nil
 }
 
 func (cl ClientMap) CleanCycle() {
@@ -22,36 +22,40 @@ var CompanySearchFields = NewSearchFields("name", "tech_stack")
 var CompanySortFields = NewSortFields("id", "created_at", "updated_at", "name")
 var CompanyInFields = NewInFields("tech_stack")
 
-func (m CompanyModel) GetVersion(id int64) (int64, error) {
+// GetVersion only matches companies that the tenant owns since the version is only needed to modify the record.
+func (m CompanyModel) GetVersion(ctx context.Context, t types.Tenant, id int64) (int64, error) {
 	query := `
 		select version
 		from companies
 		where id = $1
+		and (owner_id = $2 or $3)
 	`
 	var version int64
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return version, types.MapError(
-		m.DB.QueryRowContext(ctx, query, id).Scan(&version),
+		m.DB.QueryRowContext(ctx, query, id, t.UserID, t.All).Scan(&version),
 		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
 	)
 }
 
-func (m CompanyModel) Insert(company *types.Company) error {
+func (m CompanyModel) Insert(ctx context.Context, t types.Tenant, company *types.Company) error {
 	query := `
-		insert into companies (name, url, tech_stack)
-		values ($1, $2, $3)
-		returning id, created_at, updated_at, version
+		insert into companies (owner_id, visibility, name, url, tech_stack)
+		values ($1, $2, $3, $4, $5)
+		returning id, created_at, updated_at, coalesce(owner_id, 0), version
 	`
 	args := []any{
+		ownerArg(t),
+		company.Visibility,
 		company.Name,
 		company.URL,
 		pq.Array(company.TechStack),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return types.MapError(
@@ -59,28 +63,33 @@ func (m CompanyModel) Insert(company *types.Company) error {
 			&company.ID,
 			&company.CreatedAt,
 			&company.UpdatedAt,
+			&company.OwnerID,
 			&company.Version,
 		),
 		types.ErrorMap{".*duplicate key.*": types.ErrDuplicateKey},
 	)
 }
 
-func (m CompanyModel) GetOne(id int64) (*types.Company, error) {
+// GetOne matches companies that the tenant owns or that have been made public.
+func (m CompanyModel) GetOne(ctx context.Context, t types.Tenant, id int64) (*types.Company, error) {
 	query := `
-		select id, created_at, updated_at, name, url, tech_stack, version
+		select id, created_at, updated_at, coalesce(owner_id, 0), visibility, name, url, tech_stack, version
 		from companies
 		where id = $1
+		and (owner_id = $2 or visibility = 'public' or $3)
 	`
 	var c types.Company
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return &c, types.MapError(
-		m.DB.QueryRowContext(ctx, query, id).Scan(
+		m.DB.QueryRowContext(ctx, query, id, t.UserID, t.All).Scan(
 			&c.ID,
 			&c.CreatedAt,
 			&c.UpdatedAt,
+			&c.OwnerID,
+			&c.Visibility,
 			&c.Name,
 			&c.URL,
 			pq.Array(&c.TechStack),
@@ -90,15 +99,30 @@ func (m CompanyModel) GetOne(id int64) (*types.Company, error) {
 	)
 }
 
-func (m CompanyModel) GetMany(f Filters) ([]*types.Company, *ListMetadata, error) {
+func (m CompanyModel) GetMany(ctx context.Context, t types.Tenant, f Filters) ([]*types.Company, *ListMetadata, error) {
 	args := []any{}
 	query_parts := []string{`
-		select count(*) over (), id, created_at, updated_at, name, url, tech_stack, version
+		select
+			count(*) over (),
+			id,
+			created_at,
+			updated_at,
+			coalesce(owner_id, 0),
+			visibility,
+			name,
+			url,
+			tech_stack,
+			version
 		from companies
 	`}
 
 	where_parts := []string{}
 
+	if !t.All {
+		args = append(args, t.UserID)
+		where_parts = append(where_parts, fmt.Sprintf("(owner_id = $%d or visibility = 'public')", len(args)))
+	}
+
 	if f.Search != nil {
 		for k, v := range *f.Search {
 			args = append(args, v)
@@ -141,7 +165,7 @@ func (m CompanyModel) GetMany(f Filters) ([]*types.Company, *ListMetadata, error
 
 	slog.Debug("Assembled GetMany query", "query", query, "args", args)
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
@@ -159,6 +183,8 @@ func (m CompanyModel) GetMany(f Filters) ([]*types.Company, *ListMetadata, error
 			&c.ID,
 			&c.CreatedAt,
 			&c.UpdatedAt,
+			&c.OwnerID,
+			&c.Visibility,
 			&c.Name,
 			&c.URL,
 			pq.Array(&c.TechStack),
@@ -176,24 +202,31 @@ func (m CompanyModel) GetMany(f Filters) ([]*types.Company, *ListMetadata, error
 	return companies, &metadata, nil
 }
 
-func (m CompanyModel) Update(company *types.Company) error {
+func (m CompanyModel) Update(ctx context.Context, t types.Tenant, company *types.Company) error {
 	query := `
 		update companies
-		set name = $1, url = $2, tech_stack = $3, updated_at = $4, version = version + 1
-		where id = $5 and version = $6
+		set name = $1, url = $2, tech_stack = $3, visibility = $4, updated_at = $5, version = version + 1
+		where id = $6 and version = $7
+		and (owner_id = $8 or $9)
 		returning version
 	`
 	args := []any{
 		company.Name,
 		company.URL,
 		pq.Array(company.TechStack),
+		company.Visibility,
 		time.Now(),
 		company.ID,
 		company.Version,
+		t.UserID,
+		t.All,
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
 	return types.MapError(
-		m.DB.QueryRow(query, args...).Scan(&company.Version),
+		m.DB.QueryRowContext(ctx, query, args...).Scan(&company.Version),
 		types.ErrorMap{
 			sql.ErrNoRows:       types.ErrEditConflict,
 			".*duplicate key.*": types.ErrDuplicateKey,
@@ -202,6 +235,8 @@ func (m CompanyModel) Update(company *types.Company) error {
 }
 
 func (m CompanyModel) PartialUpdate(
+	ctx context.Context,
+	t types.Tenant,
 	id int64,
 	version int64,
 	partial *types.PartialCompany,
@@ -233,41 +268,54 @@ func (m CompanyModel) PartialUpdate(
 		i += 1
 	}
 
+	if partial.Visibility != nil {
+		query += fmt.Sprintf(", visibility = $%d", i)
+		args = append(args, *partial.Visibility)
+		i += 1
+	}
+
 	query += fmt.Sprintf(`
 		where id = $%d and version = $%d
-		returning created_at, updated_at, name, url, tech_stack, version
-	`, i, i+1)
-	args = append(args, id, version)
+		and (owner_id = $%d or $%d)
+		returning created_at, updated_at, coalesce(owner_id, 0), visibility, name, url, tech_stack, version
+	`, i, i+1, i+2, i+3)
+	args = append(args, id, version, t.UserID, t.All)
 	c := &types.Company{
 		ID: id,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return c, types.MapError(
 		m.DB.QueryRowContext(ctx, query, args...).Scan(
 			&c.CreatedAt,
 			&c.UpdatedAt,
+			&c.OwnerID,
+			&c.Visibility,
 			&c.Name,
 			&c.URL,
 			pq.Array(&c.TechStack),
 			&c.Version,
 		),
-		types.ErrorMap{sql.ErrNoRows: types.ErrEditConflict},
+		types.ErrorMap{
+			sql.ErrNoRows:       types.ErrEditConflict,
+			".*duplicate key.*": types.ErrDuplicateKey,
+		},
 	)
 }
 
-func (m CompanyModel) Delete(id int64) error {
+func (m CompanyModel) Delete(ctx context.Context, t types.Tenant, id int64) error {
 	query := `
 		delete from companies
 		where id = $1
+		and (owner_id = $2 or $3)
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.DB.ExecContext(ctx, query, id, t.UserID, t.All)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,239 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func TestCompanyGetMany(t *testing.T) {
+	eachStore(t, func(t *testing.T, models data.Models) {
+		ctx := context.Background()
+		alice := insertUser(t, models, "Alice", "alice@example.com")
+		bob := insertUser(t, models, "Bob", "bob@example.com")
+
+		seeds := []struct {
+			owner      *types.User
+			name       string
+			techStack  []string
+			visibility types.Visibility
+		}{
+			{alice, "Acme Widgets", []string{"go", "postgres"}, types.VisibilityPrivate},
+			{alice, "Blue Widgets", []string{"python"}, types.VisibilityPublic},
+			{alice, "Cobalt", []string{"go"}, types.VisibilityPrivate},
+			{bob, "Dynamo", []string{"go"}, types.VisibilityPrivate},
+			{bob, "Echo Widgets", []string{"rust", "go"}, types.VisibilityPublic},
+		}
+		for _, s := range seeds {
+			c := &types.Company{Name: s.name, TechStack: s.techStack, Visibility: s.visibility}
+			err := models.Company.Insert(ctx, types.UserTenant(s.owner.ID), c)
+			if err != nil {
+				t.Fatalf("Couldn't insert company %s: %v", s.name, err)
+			}
+		}
+
+		cases := []struct {
+			name   string
+			tenant types.Tenant
+			query  string
+			want   []string
+			meta   data.ListMetadata
+		}{
+			{
+				name:   "own and public companies",
+				tenant: types.UserTenant(alice.ID),
+				query:  "sort=name",
+				want:   []string{"Acme Widgets", "Blue Widgets", "Cobalt", "Echo Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 1, PageSize: 10, FirstPage: 1, LastPage: 1, RecordCount: 4},
+			},
+			{
+				name:   "admin sees every company",
+				tenant: types.AdminTenant(alice.ID),
+				query:  "sort=name",
+				want:   []string{"Acme Widgets", "Blue Widgets", "Cobalt", "Dynamo", "Echo Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 1, PageSize: 10, FirstPage: 1, LastPage: 1, RecordCount: 5},
+			},
+			{
+				name:   "search is a case-insensitive partial match",
+				tenant: types.UserTenant(alice.ID),
+				query:  "search_name=WIDG&sort=name",
+				want:   []string{"Acme Widgets", "Blue Widgets", "Echo Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 1, PageSize: 10, FirstPage: 1, LastPage: 1, RecordCount: 3},
+			},
+			{
+				name:   "in matches an element of the array",
+				tenant: types.UserTenant(alice.ID),
+				query:  "in_tech_stack=go&sort=name",
+				want:   []string{"Acme Widgets", "Cobalt", "Echo Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 1, PageSize: 10, FirstPage: 1, LastPage: 1, RecordCount: 3},
+			},
+			{
+				name:   "search and in are combined",
+				tenant: types.UserTenant(alice.ID),
+				query:  "search_name=widgets&in_tech_stack=go&sort=name",
+				want:   []string{"Acme Widgets", "Echo Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 1, PageSize: 10, FirstPage: 1, LastPage: 1, RecordCount: 2},
+			},
+			{
+				name:   "descending sort",
+				tenant: types.UserTenant(bob.ID),
+				query:  "sort=-name",
+				want:   []string{"Echo Widgets", "Dynamo", "Blue Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 1, PageSize: 10, FirstPage: 1, LastPage: 1, RecordCount: 3},
+			},
+			{
+				name:   "first page",
+				tenant: types.AdminTenant(alice.ID),
+				query:  "sort=name&page=1&page_size=2",
+				want:   []string{"Acme Widgets", "Blue Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 1, PageSize: 2, FirstPage: 1, LastPage: 3, RecordCount: 5},
+			},
+			{
+				name:   "last page is partial",
+				tenant: types.AdminTenant(alice.ID),
+				query:  "sort=name&page=3&page_size=2",
+				want:   []string{"Echo Widgets"},
+				meta:   data.ListMetadata{CurrentPage: 3, PageSize: 2, FirstPage: 1, LastPage: 3, RecordCount: 5},
+			},
+			{
+				name:   "page past the end is empty",
+				tenant: types.AdminTenant(alice.ID),
+				query:  "sort=name&page=4&page_size=2",
+				want:   []string{},
+			},
+			{
+				name:   "no matches",
+				tenant: types.UserTenant(alice.ID),
+				query:  "search_name=nothing",
+				want:   []string{},
+			},
+		}
+		for _, c := range cases {
+			qs, err := url.ParseQuery(c.query)
+			if err != nil {
+				t.Fatalf("%s: bad query %q: %v", c.name, c.query, err)
+			}
+			f := data.ParseFilters(qs, validator.New(), data.FilterConstraints{})
+
+			companies, meta, err := models.Company.GetMany(ctx, c.tenant, f)
+			if err != nil {
+				t.Errorf("%s: GetMany() returned an error: %v", c.name, err)
+				continue
+			}
+			got := make([]string, 0, len(companies))
+			for _, company := range companies {
+				got = append(got, company.Name)
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("%s: GetMany() = %v; want %v", c.name, got, c.want)
+			}
+			if *meta != c.meta {
+				t.Errorf("%s: GetMany() metadata = %+v; want %+v", c.name, *meta, c.meta)
+			}
+		}
+	})
+}
+
+func TestCompanyUpdates(t *testing.T) {
+	eachStore(t, func(t *testing.T, models data.Models) {
+		ctx := context.Background()
+		alice := types.UserTenant(insertUser(t, models, "Alice", "alice@example.com").ID)
+		bob := types.UserTenant(insertUser(t, models, "Bob", "bob@example.com").ID)
+
+		acme := &types.Company{Name: "Acme", TechStack: []string{"go"}, Visibility: types.VisibilityPublic}
+		initech := &types.Company{Name: "Initech", TechStack: []string{"java"}, Visibility: types.VisibilityPrivate}
+		for _, c := range []*types.Company{acme, initech} {
+			err := models.Company.Insert(ctx, alice, c)
+			if err != nil {
+				t.Fatalf("Couldn't insert company %s: %v", c.Name, err)
+			}
+		}
+		if acme.Version != 0 || acme.OwnerID != alice.UserID {
+			t.Fatalf("Insert() set version %d and owner %d", acme.Version, acme.OwnerID)
+		}
+
+		another := func() *types.Company {
+			return &types.Company{Name: "Acme", TechStack: []string{"rust"}, Visibility: types.VisibilityPrivate}
+		}
+		err := models.Company.Insert(ctx, alice, another())
+		if !errors.Is(err, types.ErrDuplicateKey) {
+			t.Errorf("Insert() of a duplicate name returned %v; want %v", err, types.ErrDuplicateKey)
+		}
+
+		// The unique key includes the owner, so other users can track a company of the same name.
+		err = models.Company.Insert(ctx, bob, another())
+		if err != nil {
+			t.Errorf("Insert() of another owner's company name returned %v", err)
+		}
+
+		acme.URL = "https://acme.example.com"
+		err = models.Company.Update(ctx, alice, acme)
+		if err != nil {
+			t.Fatalf("Update() returned an error: %v", err)
+		}
+		if acme.Version != 1 {
+			t.Errorf("Update() set version %d; want 1", acme.Version)
+		}
+
+		stale := *acme
+		stale.Version = 0
+		err = models.Company.Update(ctx, alice, &stale)
+		if !errors.Is(err, types.ErrEditConflict) {
+			t.Errorf("Update() with a stale version returned %v; want %v", err, types.ErrEditConflict)
+		}
+
+		name := "Initech"
+		stack := []string{"go", "postgres"}
+		cases := []struct {
+			name    string
+			tenant  types.Tenant
+			version int64
+			partial types.PartialCompany
+			wantErr error
+		}{
+			{
+				name:    "stale version",
+				tenant:  alice,
+				version: 0,
+				partial: types.PartialCompany{TechStack: stack},
+				wantErr: types.ErrEditConflict,
+			},
+			{
+				name:    "not the owner",
+				tenant:  bob,
+				version: 1,
+				partial: types.PartialCompany{TechStack: stack},
+				wantErr: types.ErrEditConflict,
+			},
+			{
+				name:    "duplicate name",
+				tenant:  alice,
+				version: 1,
+				partial: types.PartialCompany{Name: &name},
+				wantErr: types.ErrDuplicateKey,
+			},
+			{name: "owner", tenant: alice, version: 1, partial: types.PartialCompany{TechStack: stack}},
+			{name: "admin", tenant: types.AdminTenant(bob.UserID), version: 2, partial: types.PartialCompany{TechStack: stack}},
+		}
+		for _, c := range cases {
+			_, err := models.Company.PartialUpdate(ctx, c.tenant, acme.ID, c.version, &c.partial)
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("%s: PartialUpdate() returned %v; want %v", c.name, err, c.wantErr)
+			}
+		}
+
+		got, err := models.Company.GetOne(ctx, alice, acme.ID)
+		if err != nil {
+			t.Fatalf("GetOne() returned an error: %v", err)
+		}
+		if got.Version != 3 || got.Name != "Acme" || got.URL != acme.URL || !slices.Equal(got.TechStack, stack) {
+			t.Errorf("GetOne() after the updates = %+v", got)
+		}
+	})
+}
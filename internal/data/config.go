@@ -1,6 +1,8 @@
 package data
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -35,15 +37,115 @@ type Config struct {
 	LimitEnabled bool       `env:"LIMIT_ENABLED" envDefault:"true"`
 	LimitRPS     rate.Limit `env:"LIMIT_RPS"     envDefault:"5.0"`
 	LimitBurst   int        `env:"LIMIT_BURST"   envDefault:"10"`
+	LimitStore   string     `env:"LIMIT_STORE"   envDefault:"memory"`
+
+	LimitIPRPS           rate.Limit `env:"LIMIT_IP_RPS"           envDefault:"20.0"`
+	LimitIPBurst         int        `env:"LIMIT_IP_BURST"         envDefault:"40"`
+	LimitUserRPS         rate.Limit `env:"LIMIT_USER_RPS"         envDefault:"10.0"`
+	LimitUserBurst       int        `env:"LIMIT_USER_BURST"       envDefault:"20"`
+	LimitLoginRPS        rate.Limit `env:"LIMIT_LOGIN_RPS"        envDefault:"0.2"`
+	LimitLoginBurst      int        `env:"LIMIT_LOGIN_BURST"      envDefault:"5"`
+	LimitActivationRPS   rate.Limit `env:"LIMIT_ACTIVATION_RPS"   envDefault:"0.05"`
+	LimitActivationBurst int        `env:"LIMIT_ACTIVATION_BURST" envDefault:"3"`
+	LimitRefreshRPS      rate.Limit `env:"LIMIT_REFRESH_RPS"      envDefault:"0.2"`
+	LimitRefreshBurst    int        `env:"LIMIT_REFRESH_BURST"    envDefault:"10"`
+	LimitResetRPS        rate.Limit `env:"LIMIT_RESET_RPS"        envDefault:"0.05"`
+	LimitResetBurst      int        `env:"LIMIT_RESET_BURST"      envDefault:"3"`
+
+	RedisURL     string        `env:"REDIS_URL"     envDefault:"redis://redis:6379/0" json:"-"`
+	RedisTimeout time.Duration `env:"REDIS_TIMEOUT" envDefault:"500ms"`
+
+	MailLimitInterval time.Duration `env:"MAIL_LIMIT_INTERVAL" envDefault:"5m"`
+	MailLimitBurst    int           `env:"MAIL_LIMIT_BURST"    envDefault:"3"`
+
+	LockoutEnabled       bool          `env:"LOCKOUT_ENABLED"        envDefault:"true"`
+	LockoutEmailFailures int           `env:"LOCKOUT_EMAIL_FAILURES" envDefault:"5"`
+	LockoutIPFailures    int           `env:"LOCKOUT_IP_FAILURES"    envDefault:"20"`
+	LockoutBaseDelay     time.Duration `env:"LOCKOUT_BASE_DELAY"     envDefault:"1m"`
+	LockoutMaxDelay      time.Duration `env:"LOCKOUT_MAX_DELAY"      envDefault:"1h"`
+	LockoutWindow        time.Duration `env:"LOCKOUT_WINDOW"         envDefault:"15m"`
 
 	ClientCleanupInterval time.Duration `env:"CLIENT_CLEANUP_INTERVAL" envDefault:"1m"`
 	ClientCleanupTimeout  time.Duration `env:"CLIENT_CLEANUP_TIMEOUT"  envDefault:"3m"`
 
-	ActivationTTL time.Duration `env:"ACTIVATION_TTL" envDefault:"72h"`
-	AuthTTL       time.Duration `env:"AUTH_TTL" envDefault:"1h"`
+	ActivationTTL    time.Duration `env:"ACTIVATION_TTL"     envDefault:"72h"`
+	AuthTTL          time.Duration `env:"AUTH_TTL"           envDefault:"1h"`
+	RefreshTTL       time.Duration `env:"REFRESH_TTL"        envDefault:"720h"`
+	MFAPendingTTL    time.Duration `env:"MFA_PENDING_TTL"    envDefault:"5m"`
+	PasswordResetTTL time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"45m"`
+
+	TokenMode          types.TokenMode `env:"TOKEN_MODE"           envDefault:"opaque"`
+	JWTAlgorithm       string          `env:"JWT_ALGORITHM"        envDefault:"EdDSA"`
+	JWTKeys            []string        `env:"JWT_KEYS"                                   json:"-"`
+	JWTIssuer          string          `env:"JWT_ISSUER"           envDefault:"the-hunt"`
+	JWTDenylistRefresh time.Duration   `env:"JWT_DENYLIST_REFRESH" envDefault:"10s"`
 
 	AdminEmail    types.Email   `env:"ADMIN_EMAIL"`
 	AdminPassword types.PlainPW `env:"ADMIN_PASSWORD" json:"-"`
 
 	CORSTrustOrigins []string `env:"CORS_TRUST_ORIGINS"`
+
+	MetricsPort  int    `env:"METRICS_PORT"  envDefault:"4001"`
+	MetricsToken string `env:"METRICS_TOKEN" json:"-"`
+
+	TracingEnabled     bool    `env:"TRACING_ENABLED"      envDefault:"false"`
+	TracingEndpoint    string  `env:"TRACING_ENDPOINT"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+
+	DefaultRoles []string `env:"DEFAULT_ROLES" envDefault:"member"`
+
+	MFAIssuer string `env:"MFA_ISSUER" envDefault:"The Hunt"`
+
+	OIDCIssuerURL    string        `env:"OIDC_ISSUER_URL"`
+	OIDCClientID     string        `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string        `env:"OIDC_CLIENT_SECRET" json:"-"`
+	OIDCRedirectURL  string        `env:"OIDC_REDIRECT_URL"`
+	OIDCScopes       []string      `env:"OIDC_SCOPES"        envDefault:"openid,email,profile"`
+	OIDCLoginTTL     time.Duration `env:"OIDC_LOGIN_TTL"     envDefault:"10m"`
+}
+
+// DSN is the connection string for the database that the config points at.
+func (cfg Config) DSN() string {
+	return fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DBUser,
+		cfg.DBPswd,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+	)
+}
+
+// Validate catches settings that parse fine but can't work. A rate limit of zero would never let a request through and
+// would leave the limiters dividing by zero, so turning limits off is left to LIMIT_ENABLED.
+func (cfg Config) Validate() error {
+	rates := []struct {
+		name  string
+		limit rate.Limit
+		burst int
+	}{
+		{"LIMIT", cfg.LimitRPS, cfg.LimitBurst},
+		{"LIMIT_IP", cfg.LimitIPRPS, cfg.LimitIPBurst},
+		{"LIMIT_USER", cfg.LimitUserRPS, cfg.LimitUserBurst},
+		{"LIMIT_LOGIN", cfg.LimitLoginRPS, cfg.LimitLoginBurst},
+		{"LIMIT_ACTIVATION", cfg.LimitActivationRPS, cfg.LimitActivationBurst},
+		{"LIMIT_REFRESH", cfg.LimitRefreshRPS, cfg.LimitRefreshBurst},
+		{"LIMIT_RESET", cfg.LimitResetRPS, cfg.LimitResetBurst},
+	}
+	for _, r := range rates {
+		if r.limit <= 0 {
+			return fmt.Errorf("%s_RPS must be greater than zero", r.name)
+		}
+		if r.burst <= 0 {
+			return fmt.Errorf("%s_BURST must be greater than zero", r.name)
+		}
+	}
+
+	if cfg.MailLimitInterval <= 0 {
+		return errors.New("MAIL_LIMIT_INTERVAL must be greater than zero")
+	}
+	if cfg.MailLimitBurst <= 0 {
+		return errors.New("MAIL_LIMIT_BURST must be greater than zero")
+	}
+	return nil
 }
@@ -0,0 +1,43 @@
+package data_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caarlos0/env/v11"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "zero route rate", env: map[string]string{"LIMIT_LOGIN_RPS": "0"}, wantErr: "LIMIT_LOGIN_RPS"},
+		{name: "negative rate", env: map[string]string{"LIMIT_IP_RPS": "-1"}, wantErr: "LIMIT_IP_RPS"},
+		{name: "zero burst", env: map[string]string{"LIMIT_RESET_BURST": "0"}, wantErr: "LIMIT_RESET_BURST"},
+		{name: "zero mail interval", env: map[string]string{"MAIL_LIMIT_INTERVAL": "0s"}, wantErr: "MAIL_LIMIT_INTERVAL"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var cfg data.Config
+			err := env.ParseWithOptions(&cfg, env.Options{Environment: c.env})
+			if err != nil {
+				t.Fatalf("Couldn't parse config: %v", err)
+			}
+
+			err = cfg.Validate()
+			if c.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() returned %v; want no error", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("Validate() returned %v; want an error mentioning %s", err, c.wantErr)
+			}
+		})
+	}
+}
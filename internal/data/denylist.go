@@ -0,0 +1,93 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Denylist keeps an in-memory copy of the token families that have been revoked so that signed access tokens can be
+// checked without a database round-trip. The copy is refreshed periodically, so a revocation made by another instance
+// takes up to the refresh interval to apply here.
+type Denylist struct {
+	DB              *sql.DB
+	CFG             ModelConfig
+	RefreshInterval time.Duration
+	families        map[string]time.Time
+	mutex           *sync.RWMutex
+}
+
+func NewDenylist(db *sql.DB, cfg Config) *Denylist {
+	return &Denylist{
+		DB:              db,
+		CFG:             NewModelConfig(cfg),
+		RefreshInterval: cfg.JWTDenylistRefresh,
+		families:        make(map[string]time.Time),
+		mutex:           &sync.RWMutex{},
+	}
+}
+
+// Contains reports whether the token family has been revoked.
+func (dl *Denylist) Contains(family string) bool {
+	dl.mutex.RLock()
+	defer dl.mutex.RUnlock()
+	expiresAt, ok := dl.families[family]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// Refresh prunes expired entries from the denylist table and reloads the rest.
+func (dl *Denylist) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, dl.CFG.QueryTimeout)
+	defer cancel()
+
+	query := `
+		delete from token_denylist
+		where expires_at <= now()
+	`
+	_, err := dl.DB.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		select family, expires_at
+		from token_denylist
+	`
+	rows, err := dl.DB.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	families := make(map[string]time.Time)
+	for rows.Next() {
+		var family string
+		var expiresAt time.Time
+		err := rows.Scan(&family, &expiresAt)
+		if err != nil {
+			return err
+		}
+		families[family] = expiresAt
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+	dl.families = families
+	return nil
+}
+
+func (dl *Denylist) RefreshCycle() {
+	for {
+		time.Sleep(dl.RefreshInterval)
+		slog.Debug("Refreshing token denylist")
+		err := dl.Refresh(context.Background())
+		if err != nil {
+			slog.Error("Couldn't refresh token denylist", "error", err)
+		}
+	}
+}
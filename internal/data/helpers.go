@@ -17,6 +17,7 @@ type ErrorPackage struct {
 	Message    string `json:"message"`
 	LogMessage string `json:"-"`
 	Details    any    `json:"details,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
 	StatusCode int    `json:"-"`
 }
 
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type LoginFailureModel struct {
+	DB  *sql.DB
+	CFG ModelConfig
+}
+
+// LockedUntil returns the latest lockout that applies to either the email or the IP address. If neither is locked,
+// the zero time is returned.
+func (m LoginFailureModel) LockedUntil(ctx context.Context, email string, ip string) (time.Time, error) {
+	query := `
+		select coalesce(max(locked_until), 'epoch'::timestamptz)
+		from login_failures
+		where ((kind = $1 and subject = $2) or (kind = $3 and subject = $4))
+		and locked_until > $5
+	`
+	args := []any{
+		types.FailureByEmail,
+		email,
+		types.FailureByIP,
+		ip,
+		time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	var until time.Time
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&until)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if until.Unix() == 0 {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// RecordFailure counts another failed login for the subject and applies the policy's lockout. A count that has gone
+// stale under the policy's window starts over. The new failure count is returned so that callers can react when a
+// lockout first kicks in.
+func (m LoginFailureModel) RecordFailure(
+	ctx context.Context,
+	kind types.LoginFailureKind,
+	subject string,
+	policy types.LockoutPolicy,
+) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		insert into login_failures (kind, subject, failures)
+		values ($1, $2, 1)
+		on conflict (kind, subject) do update
+		set
+			failures = case
+				when greatest(login_failures.last_failed_at, login_failures.locked_until) < $3 then 1
+				else login_failures.failures + 1
+			end,
+			last_failed_at = now()
+		returning failures
+	`
+
+	var failures int
+	err = tx.QueryRowContext(ctx, query, kind, subject, time.Now().Add(-policy.Window)).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	delay := policy.Delay(failures)
+	if delay > 0 {
+		query = `
+			update login_failures
+			set locked_until = $1
+			where kind = $2 and subject = $3
+		`
+		_, err = tx.ExecContext(ctx, query, time.Now().Add(delay), kind, subject)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return failures, tx.Commit()
+}
+
+// Reset clears the failure count and any lockout for the subject.
+func (m LoginFailureModel) Reset(ctx context.Context, kind types.LoginFailureKind, subject string) error {
+	query := `
+		delete from login_failures
+		where kind = $1 and subject = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, kind, subject)
+	return err
+}
@@ -0,0 +1,65 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/pgtest"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// The in-memory stores don't model lockouts, so failure counting is only checked against Postgres.
+func TestLoginFailureWindow(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.Open(t)
+	models := data.NewModels(db, data.ModelConfig{QueryTimeout: 5 * time.Second})
+	policy := types.LockoutPolicy{MaxFailures: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: 15 * time.Minute}
+
+	age := func(lastFailed time.Duration, lockedUntil *time.Duration) {
+		t.Helper()
+		var until any
+		if lockedUntil != nil {
+			until = time.Now().Add(*lockedUntil)
+		}
+		_, err := db.ExecContext(
+			ctx,
+			"update login_failures set last_failed_at = $1, locked_until = $2 where subject = 'a@example.com'",
+			time.Now().Add(lastFailed),
+			until,
+		)
+		if err != nil {
+			t.Fatalf("Couldn't age the failures: %v", err)
+		}
+	}
+	ago := func(d time.Duration) *time.Duration {
+		d = -d
+		return &d
+	}
+
+	steps := []struct {
+		name  string
+		setup func()
+		want  int
+	}{
+		{name: "first failure", want: 1},
+		{name: "failure inside the window", setup: func() { age(-5*time.Minute, nil) }, want: 2},
+		{name: "failure after the window", setup: func() { age(-time.Hour, nil) }, want: 1},
+		{name: "failure after the window but a recent lockout", setup: func() { age(-time.Hour, ago(time.Minute)) }, want: 2},
+		{name: "failure after the window and the lockout", setup: func() { age(-time.Hour, ago(time.Hour)) }, want: 1},
+	}
+
+	for _, s := range steps {
+		if s.setup != nil {
+			s.setup()
+		}
+		got, err := models.LoginFailure.RecordFailure(ctx, types.FailureByEmail, "a@example.com", policy)
+		if err != nil {
+			t.Fatalf("%s: RecordFailure() returned an error: %v", s.name, err)
+		}
+		if got != s.want {
+			t.Errorf("%s: RecordFailure() = %d; want %d", s.name, got, s.want)
+		}
+	}
+}
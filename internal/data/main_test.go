@@ -0,0 +1,42 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/pgtest"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func TestMain(m *testing.M) {
+	pgtest.Main(m)
+}
+
+// eachStore runs the test against Postgres and against the in-memory stores so that both keep the same semantics.
+// The Postgres run is skipped when no server is available.
+func eachStore(t *testing.T, test func(t *testing.T, models data.Models)) {
+	t.Run("postgres", func(t *testing.T) {
+		test(t, data.NewModels(pgtest.Open(t), data.ModelConfig{QueryTimeout: 5 * time.Second}))
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, data.NewMemoryModels(data.NewMemoryStore()))
+	})
+}
+
+func insertUser(t *testing.T, models data.Models, name string, email string) *types.User {
+	t.Helper()
+
+	user := &types.User{
+		Name:           name,
+		Email:          types.Email(email),
+		HashedPassword: types.HashPW("not-a-real-hash"),
+		Activated:      true,
+	}
+	err := models.User.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Couldn't insert user %s: %v", email, err)
+	}
+	return user
+}
@@ -0,0 +1,975 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// MemoryStore keeps companies, users, tokens and permissions in memory. It backs the in-memory models, which follow
+// the same rules as the database-backed ones (tenancy, versions, unique keys and cascading deletes) so that handlers
+// can be tested without Postgres. All of the models created from one store share its records and its lock.
+type MemoryStore struct {
+	mutex     sync.Mutex
+	lastIDs   map[string]int64
+	companies map[int64]*types.Company
+	users     map[int64]*types.User
+	tokens    map[int64]*memoryToken
+	perms     map[int64]*types.PermissionSet
+}
+
+// memoryToken adds the columns that the tokens table tracks but types.Token doesn't carry.
+type memoryToken struct {
+	types.Token
+	createdAt  time.Time
+	lastUsedAt *time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		lastIDs:   make(map[string]int64),
+		companies: make(map[int64]*types.Company),
+		users:     make(map[int64]*types.User),
+		tokens:    make(map[int64]*memoryToken),
+		perms:     make(map[int64]*types.PermissionSet),
+	}
+}
+
+// NewMemoryModels backs the company, user, token and permission models with the store. The posting, application, API
+// key, role, MFA, login failure and OIDC models don't have in-memory versions and are left nil. Calling a nil model
+// panics, so callers must supply their own (usually a stub) for anything that the code under test touches.
+func NewMemoryModels(s *MemoryStore) Models {
+	return Models{
+		Company:    MemoryCompanyModel{Store: s},
+		User:       MemoryUserModel{Store: s},
+		Token:      MemoryTokenModel{Store: s},
+		Permission: MemoryPermissionModel{Store: s},
+	}
+}
+
+// nextID works like the bigserial id column of the table.
+func (s *MemoryStore) nextID(table string) int64 {
+	s.lastIDs[table] += 1
+	return s.lastIDs[table]
+}
+
+// deleteUserRecords cascades the deletion of a user the way the foreign keys do.
+func (s *MemoryStore) deleteUserRecords(userID int64) {
+	for id, t := range s.tokens {
+		if t.UserID == userID {
+			delete(s.tokens, id)
+		}
+	}
+	delete(s.perms, userID)
+}
+
+// matchSearch applies the case-insensitive regular expression matching that the ~* operator does. A record matches
+// when every searched field has a value that matches.
+func matchSearch[T any](record T, f Filters, field func(T, string) []string) (bool, error) {
+	if f.Search == nil {
+		return true, nil
+	}
+	for k, v := range *f.Search {
+		rex, err := regexp.Compile("(?i)" + v)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression for %s: %w", k, err)
+		}
+		if !slices.ContainsFunc(field(record, k), rex.MatchString) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sortRecords orders the records by the sort keys the way an order by clause would. Records that tie on every key are
+// kept in ID order so that pages are stable.
+func sortRecords[T any](records []T, f Filters, field func(T, string) any) {
+	slices.SortStableFunc(records, func(a T, b T) int {
+		if f.Sort == nil {
+			return 0
+		}
+		for k, dir := range f.Sort.FromOldest() {
+			c := compareValues(field(a, k), field(b, k))
+			if dir == SortDesc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+func compareValues(a any, b any) int {
+	switch av := a.(type) {
+	case int64:
+		return cmp.Compare(av, b.(int64))
+	case string:
+		return strings.Compare(av, b.(string))
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	default:
+		panic(fmt.Sprintf("can't compare values of type %T", a))
+	}
+}
+
+// pageRecords cuts out the requested page. Like the count(*) over () in the queries, the record count is only known
+// when the page isn't empty.
+func pageRecords[T any](records []T, f Filters) ([]T, *ListMetadata) {
+	recordCount := len(records)
+	if f.Page != nil && f.PageSize != nil {
+		start := min((*f.Page-1)**f.PageSize, len(records))
+		end := min(start+*f.PageSize, len(records))
+		records = records[start:end]
+	}
+	if len(records) == 0 {
+		recordCount = 0
+	}
+	metadata := NewListMetadata(f, recordCount)
+	return records, &metadata
+}
+
+// sortedIDs lists the keys of a table in insertion order.
+func sortedIDs[T any](table map[int64]T) []int64 {
+	ids := make([]int64, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// MemoryCompanyModel is an in-memory CompanyStore.
+type MemoryCompanyModel struct {
+	Store *MemoryStore
+}
+
+func copyCompany(c *types.Company) *types.Company {
+	cc := *c
+	cc.TechStack = slices.Clone(c.TechStack)
+	return &cc
+}
+
+// visible matches the rows that GetOne and GetMany may return to the tenant.
+func visible(t types.Tenant, c *types.Company) bool {
+	return t.Owns(c.OwnerID) || c.Visibility == types.VisibilityPublic
+}
+
+// hasCompanyName enforces the unique key on the owner and name of a company.
+func (s *MemoryStore) hasCompanyName(ownerID int64, name string, exceptID int64) bool {
+	for id, c := range s.companies {
+		if id != exceptID && c.OwnerID == ownerID && c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MemoryCompanyModel) GetVersion(_ context.Context, t types.Tenant, id int64) (int64, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	c, ok := m.Store.companies[id]
+	if !ok || !t.Owns(c.OwnerID) {
+		return 0, types.ErrRecordNotFound
+	}
+	return c.Version, nil
+}
+
+func (m MemoryCompanyModel) Insert(_ context.Context, t types.Tenant, company *types.Company) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	if m.Store.hasCompanyName(t.UserID, company.Name, 0) {
+		return types.ErrDuplicateKey
+	}
+
+	now := time.Now()
+	company.ID = m.Store.nextID("companies")
+	company.CreatedAt = now
+	company.UpdatedAt = now
+	company.OwnerID = t.UserID
+	company.Version = 0
+	m.Store.companies[company.ID] = copyCompany(company)
+	return nil
+}
+
+func (m MemoryCompanyModel) GetOne(_ context.Context, t types.Tenant, id int64) (*types.Company, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	c, ok := m.Store.companies[id]
+	if !ok || !visible(t, c) {
+		return &types.Company{}, types.ErrRecordNotFound
+	}
+	return copyCompany(c), nil
+}
+
+func companySearchField(c *types.Company, key string) []string {
+	if key == "tech_stack" {
+		return c.TechStack
+	}
+	return []string{c.Name}
+}
+
+func companySortField(c *types.Company, key string) any {
+	switch key {
+	case "created_at":
+		return c.CreatedAt
+	case "updated_at":
+		return c.UpdatedAt
+	case "name":
+		return c.Name
+	default:
+		return c.ID
+	}
+}
+
+func (m MemoryCompanyModel) GetMany(
+	_ context.Context,
+	t types.Tenant,
+	f Filters,
+) ([]*types.Company, *ListMetadata, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	companies := make([]*types.Company, 0, 10)
+	for _, id := range sortedIDs(m.Store.companies) {
+		c := m.Store.companies[id]
+		if !t.All && !visible(t, c) {
+			continue
+		}
+
+		ok, err := matchSearch(c, f, companySearchField)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if f.In != nil && !matchIn(c.TechStack, *f.In) {
+			continue
+		}
+
+		companies = append(companies, copyCompany(c))
+	}
+
+	sortRecords(companies, f, companySortField)
+	companies, metadata := pageRecords(companies, f)
+	return companies, metadata, nil
+}
+
+// matchIn checks that every value in the in filter is an element of the array. Companies only have one array column,
+// so the key isn't needed.
+func matchIn(values []string, in InMap) bool {
+	for _, v := range in {
+		if !slices.Contains(values, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m MemoryCompanyModel) Update(_ context.Context, t types.Tenant, company *types.Company) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	c, ok := m.Store.companies[company.ID]
+	if !ok || c.Version != company.Version || !t.Owns(c.OwnerID) {
+		return types.ErrEditConflict
+	}
+	if m.Store.hasCompanyName(c.OwnerID, company.Name, c.ID) {
+		return types.ErrDuplicateKey
+	}
+
+	c.Name = company.Name
+	c.URL = company.URL
+	c.TechStack = slices.Clone(company.TechStack)
+	c.Visibility = company.Visibility
+	c.UpdatedAt = time.Now()
+	c.Version += 1
+	company.Version = c.Version
+	return nil
+}
+
+func (m MemoryCompanyModel) PartialUpdate(
+	_ context.Context,
+	t types.Tenant,
+	id int64,
+	version int64,
+	partial *types.PartialCompany,
+) (*types.Company, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	c, ok := m.Store.companies[id]
+	if !ok || c.Version != version || !t.Owns(c.OwnerID) {
+		return &types.Company{ID: id}, types.ErrEditConflict
+	}
+	if partial.Name != nil && m.Store.hasCompanyName(c.OwnerID, *partial.Name, c.ID) {
+		return &types.Company{ID: id}, types.ErrDuplicateKey
+	}
+
+	if partial.Name != nil {
+		c.Name = *partial.Name
+	}
+	if partial.URL != nil {
+		c.URL = *partial.URL
+	}
+	if partial.TechStack != nil {
+		c.TechStack = slices.Clone(partial.TechStack)
+	}
+	if partial.Visibility != nil {
+		c.Visibility = *partial.Visibility
+	}
+	c.UpdatedAt = time.Now()
+	c.Version += 1
+	return copyCompany(c), nil
+}
+
+func (m MemoryCompanyModel) Delete(_ context.Context, t types.Tenant, id int64) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	c, ok := m.Store.companies[id]
+	if !ok || !t.Owns(c.OwnerID) {
+		return types.ErrRecordNotFound
+	}
+	delete(m.Store.companies, id)
+	return nil
+}
+
+// MemoryUserModel is an in-memory UserStore.
+type MemoryUserModel struct {
+	Store *MemoryStore
+}
+
+// copyUser returns the user without the password hash, which only GetForLogin selects.
+func copyUser(u *types.User) *types.User {
+	uc := *u
+	uc.HashedPassword = nil
+	return &uc
+}
+
+// hasEmail enforces the unique key on the email column. Emails are stored as citext, so case doesn't matter.
+func (s *MemoryStore) hasEmail(email types.Email, exceptID int64) bool {
+	for id, u := range s.users {
+		if id != exceptID && strings.EqualFold(string(u.Email), string(email)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) findByEmail(email types.Email) (*types.User, bool) {
+	for _, u := range s.users {
+		if strings.EqualFold(string(u.Email), string(email)) {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// findToken matches an unexpired token by its hash and scope.
+func (s *MemoryStore) findToken(hash []byte, scope types.TokenScope) (*memoryToken, bool) {
+	now := time.Now()
+	for _, t := range s.tokens {
+		if string(t.Hash) == string(hash) && t.Scope == scope && t.ExpiresAt.After(now) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (m MemoryUserModel) GetVersion(_ context.Context, id int64) (int64, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	u, ok := m.Store.users[id]
+	if !ok {
+		return 0, types.ErrRecordNotFound
+	}
+	return u.Version, nil
+}
+
+func (m MemoryUserModel) Insert(_ context.Context, user *types.User) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	if m.Store.hasEmail(user.Email, 0) {
+		return types.ErrDuplicateKey
+	}
+
+	now := time.Now()
+	user.ID = m.Store.nextID("users")
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.Version = 1
+	u := *user
+	u.PlainPassword = ""
+	m.Store.users[user.ID] = &u
+	return nil
+}
+
+func (m MemoryUserModel) GetOne(_ context.Context, id int64) (*types.User, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	u, ok := m.Store.users[id]
+	if !ok {
+		return &types.User{}, types.ErrRecordNotFound
+	}
+	return copyUser(u), nil
+}
+
+func (m MemoryUserModel) GetForLogin(ctx context.Context, l *types.Login) (*types.User, error) {
+	m.Store.mutex.Lock()
+	u, ok := m.Store.findByEmail(l.Email)
+	var uc types.User
+	if ok {
+		uc = *u
+	}
+	m.Store.mutex.Unlock()
+
+	if !ok {
+		return nil, types.ErrRecordNotFound
+	}
+
+	if !uc.Activated {
+		return nil, types.ErrUserNotActivated
+	}
+
+	err := uc.HashedPassword.Compare(ctx, l.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &uc, nil
+}
+
+func (m MemoryUserModel) GetByEmail(_ context.Context, email types.Email) (*types.User, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	u, ok := m.Store.findByEmail(email)
+	if !ok {
+		return &types.User{}, types.ErrRecordNotFound
+	}
+	return copyUser(u), nil
+}
+
+// GetForToken only fills in the columns that the database query selects.
+func (m MemoryUserModel) GetForToken(_ context.Context, t types.Token) (*types.User, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	tok, ok := m.Store.findToken(t.Hash, t.Scope)
+	if !ok {
+		return &types.User{}, types.ErrRecordNotFound
+	}
+	u, ok := m.Store.users[tok.UserID]
+	if !ok {
+		return &types.User{}, types.ErrRecordNotFound
+	}
+	return &types.User{
+		ID:        u.ID,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		Name:      u.Name,
+		Email:     u.Email,
+		Version:   u.Version,
+	}, nil
+}
+
+func userSearchField(u *types.User, key string) []string {
+	if key == "email" {
+		return []string{string(u.Email)}
+	}
+	return []string{u.Name}
+}
+
+func userSortField(u *types.User, key string) any {
+	switch key {
+	case "created_at":
+		return u.CreatedAt
+	case "updated_at":
+		return u.UpdatedAt
+	case "name":
+		return u.Name
+	case "email":
+		return strings.ToLower(string(u.Email))
+	default:
+		return u.ID
+	}
+}
+
+func (m MemoryUserModel) GetMany(_ context.Context, f Filters) ([]*types.User, *ListMetadata, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	users := make([]*types.User, 0, 10)
+	for _, id := range sortedIDs(m.Store.users) {
+		u := m.Store.users[id]
+		ok, err := matchSearch(u, f, userSearchField)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			users = append(users, copyUser(u))
+		}
+	}
+
+	sortRecords(users, f, userSortField)
+	users, metadata := pageRecords(users, f)
+	return users, metadata, nil
+}
+
+func (m MemoryUserModel) Update(_ context.Context, user *types.User) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	u, ok := m.Store.users[user.ID]
+	if !ok || u.Version != user.Version {
+		return types.ErrEditConflict
+	}
+	if m.Store.hasEmail(user.Email, u.ID) {
+		return types.ErrDuplicateKey
+	}
+
+	u.Name = user.Name
+	u.Email = user.Email
+	u.UpdatedAt = time.Now()
+	u.Version += 1
+	user.Version = u.Version
+	return nil
+}
+
+func (m MemoryUserModel) Activate(_ context.Context, token types.Token) (int64, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	u, ok := m.Store.users[token.UserID]
+	if !ok {
+		return 0, types.ErrNoTokenMatch
+	}
+	u.Activated = true
+	return u.ID, nil
+}
+
+func (m MemoryUserModel) DeleteTokensForUser(_ context.Context, userID int64, scope string) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	m.Store.deleteTokens(func(t *memoryToken) bool {
+		return t.UserID == userID && string(t.Scope) == scope
+	})
+	return nil
+}
+
+func (m MemoryUserModel) PartialUpdate(
+	_ context.Context,
+	id int64,
+	version int64,
+	partial *types.PartialUser,
+) (*types.User, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	u, ok := m.Store.users[id]
+	if !ok || u.Version != version {
+		return &types.User{ID: id}, types.ErrEditConflict
+	}
+	if partial.Email != nil && m.Store.hasEmail(*partial.Email, u.ID) {
+		return &types.User{ID: id}, types.ErrDuplicateKey
+	}
+
+	if partial.Name != nil {
+		u.Name = *partial.Name
+	}
+	if partial.Email != nil {
+		u.Email = *partial.Email
+	}
+	if partial.HashedPassword != nil {
+		u.HashedPassword = *partial.HashedPassword
+	}
+	u.UpdatedAt = time.Now()
+	u.Version += 1
+	return &types.User{
+		ID:        u.ID,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		Name:      u.Name,
+		Email:     u.Email,
+		Version:   u.Version,
+	}, nil
+}
+
+func (m MemoryUserModel) Delete(_ context.Context, id int64) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	if _, ok := m.Store.users[id]; !ok {
+		return types.ErrRecordNotFound
+	}
+	delete(m.Store.users, id)
+	m.Store.deleteUserRecords(id)
+	return nil
+}
+
+func (m MemoryUserModel) SetAdmin(_ context.Context, id int64) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	u, ok := m.Store.users[id]
+	if !ok {
+		return types.ErrRecordNotFound
+	}
+	u.IsAdmin = true
+	u.UpdatedAt = time.Now()
+	u.Version += 1
+	return nil
+}
+
+// MemoryTokenModel is an in-memory TokenStore.
+type MemoryTokenModel struct {
+	Store *MemoryStore
+}
+
+// deleteTokens removes every token that matches and reports how many there were.
+func (s *MemoryStore) deleteTokens(match func(*memoryToken) bool) int {
+	count := 0
+	for id, t := range s.tokens {
+		if match(t) {
+			delete(s.tokens, id)
+			count += 1
+		}
+	}
+	return count
+}
+
+func (s *MemoryStore) insertToken(t *types.Token) error {
+	if _, ok := s.users[t.UserID]; !ok {
+		return fmt.Errorf("insert or update on table \"tokens\" violates foreign key constraint: user %d", t.UserID)
+	}
+	t.ID = s.nextID("tokens")
+	s.tokens[t.ID] = &memoryToken{
+		Token: types.Token{
+			ID:        t.ID,
+			Hash:      slices.Clone(t.Hash),
+			UserID:    t.UserID,
+			ExpiresAt: t.ExpiresAt,
+			Scope:     t.Scope,
+			IP:        t.IP,
+			UserAgent: t.UserAgent,
+			Family:    t.Family,
+		},
+		createdAt: time.Now(),
+	}
+	return nil
+}
+
+func (m MemoryTokenModel) New(
+	ctx context.Context,
+	userID int64,
+	ttl time.Duration,
+	scope types.TokenScope,
+) (*types.Token, error) {
+	token := types.GenerateToken(userID, ttl, scope)
+	err := m.Insert(ctx, token)
+	return token, err
+}
+
+func (m MemoryTokenModel) Insert(_ context.Context, t *types.Token) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	return m.Store.insertToken(t)
+}
+
+// InsertFamily adds all of the tokens or none of them.
+func (m MemoryTokenModel) InsertFamily(_ context.Context, tokens ...*types.Token) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	for _, t := range tokens {
+		if _, ok := m.Store.users[t.UserID]; !ok {
+			return fmt.Errorf("insert or update on table \"tokens\" violates foreign key constraint: user %d", t.UserID)
+		}
+	}
+	for _, t := range tokens {
+		err := m.Store.insertToken(t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rotate follows the same rules as TokenModel.Rotate, including revoking the whole family when a rotated token is
+// presented again.
+func (m MemoryTokenModel) Rotate(
+	_ context.Context,
+	pt types.PlainToken,
+	access *types.Token,
+	refresh *types.Token,
+) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	old, ok := m.Store.findToken(types.Hash(string(pt)), types.ScopeRefresh)
+	if !ok {
+		return types.ErrRecordNotFound
+	}
+
+	if old.RotatedAt != nil {
+		m.Store.deleteTokens(func(t *memoryToken) bool {
+			return old.Family != "" && t.Family == old.Family
+		})
+		return types.ErrTokenReused
+	}
+
+	now := time.Now()
+	old.RotatedAt = &now
+
+	for _, t := range []*types.Token{access, refresh} {
+		t.UserID = old.UserID
+		t.Family = old.Family
+		err := m.Store.insertToken(t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MemoryTokenModel) GetOne(_ context.Context, pt types.PlainToken, scope types.TokenScope) (*types.Token, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	t, ok := m.Store.findToken(types.Hash(string(pt)), scope)
+	if !ok {
+		return &types.Token{}, types.ErrRecordNotFound
+	}
+	return &types.Token{
+		ID:         t.ID,
+		UserID:     t.UserID,
+		ExpiresAt:  t.ExpiresAt,
+		Scope:      t.Scope,
+		IP:         t.IP,
+		UserAgent:  t.UserAgent,
+		LastUsedAt: t.lastUsedAt,
+	}, nil
+}
+
+func (m MemoryTokenModel) DeleteForUser(_ context.Context, scope string, userID int64) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	m.Store.deleteTokens(func(t *memoryToken) bool {
+		return t.UserID == userID && string(t.Scope) == scope
+	})
+	return nil
+}
+
+func (m MemoryTokenModel) Touch(_ context.Context, id int64) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	if t, ok := m.Store.tokens[id]; ok {
+		now := time.Now()
+		t.lastUsedAt = &now
+	}
+	return nil
+}
+
+func (m MemoryTokenModel) GetSessionsForUser(_ context.Context, userID int64) ([]*types.Session, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	now := time.Now()
+	sessions := make([]*types.Session, 0, 4)
+	for _, id := range sortedIDs(m.Store.tokens) {
+		t := m.Store.tokens[id]
+		if t.UserID != userID || t.Scope != types.ScopeAuthentication || !t.ExpiresAt.After(now) {
+			continue
+		}
+		sessions = append(sessions, &types.Session{
+			ID:         t.ID,
+			CreatedAt:  t.createdAt,
+			LastUsedAt: t.lastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+			IP:         t.IP,
+			UserAgent:  t.UserAgent,
+		})
+	}
+	slices.SortStableFunc(sessions, func(a *types.Session, b *types.Session) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	return sessions, nil
+}
+
+func (m MemoryTokenModel) DeleteSession(_ context.Context, userID int64, id int64) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	var family string
+	if t, ok := m.Store.tokens[id]; ok && t.Scope == types.ScopeAuthentication {
+		family = t.Family
+	}
+
+	count := m.Store.deleteTokens(func(t *memoryToken) bool {
+		if t.UserID != userID {
+			return false
+		}
+		return (t.ID == id && t.Scope == types.ScopeAuthentication) || (family != "" && t.Family == family)
+	})
+	if count == 0 {
+		return types.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m MemoryTokenModel) GetAllForUser(_ context.Context, userID int64) ([]*types.TokenInfo, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	tokens := make([]*types.TokenInfo, 0, 4)
+	for _, id := range sortedIDs(m.Store.tokens) {
+		t := m.Store.tokens[id]
+		if t.UserID != userID {
+			continue
+		}
+		tokens = append(tokens, &types.TokenInfo{
+			ID:         t.ID,
+			UserID:     t.UserID,
+			Scope:      t.Scope,
+			Family:     t.Family,
+			CreatedAt:  t.createdAt,
+			LastUsedAt: t.lastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+			RotatedAt:  t.RotatedAt,
+			IP:         t.IP,
+			UserAgent:  t.UserAgent,
+		})
+	}
+	return tokens, nil
+}
+
+func (m MemoryTokenModel) Delete(_ context.Context, id int64) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	var family string
+	if t, ok := m.Store.tokens[id]; ok {
+		family = t.Family
+	}
+
+	count := m.Store.deleteTokens(func(t *memoryToken) bool {
+		return t.ID == id || (family != "" && t.Family == family)
+	})
+	if count == 0 {
+		return types.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m MemoryTokenModel) DeleteExpired(_ context.Context) (int64, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	now := time.Now()
+	count := m.Store.deleteTokens(func(t *memoryToken) bool {
+		return !t.ExpiresAt.After(now)
+	})
+	return int64(count), nil
+}
+
+// MemoryPermissionModel is an in-memory PermissionStore. It only knows about permissions granted directly to users;
+// roles aren't modeled, so they don't contribute to GetForUser.
+type MemoryPermissionModel struct {
+	Store *MemoryStore
+}
+
+// memoryPermCodes are the codes seeded by the migrations.
+var memoryPermCodes = []types.PermCode{
+	types.ApplicationRead,
+	types.ApplicationWrite,
+	types.CompanyRead,
+	types.CompanyWrite,
+	types.PermissionAdmin,
+	types.PostingRead,
+	types.PostingWrite,
+	types.UserRead,
+	types.UserWrite,
+}
+
+func (s *MemoryStore) grants(userID int64) *types.PermissionSet {
+	ps, ok := s.perms[userID]
+	if !ok {
+		ps = types.NewPermissionSet()
+		s.perms[userID] = ps
+	}
+	return ps
+}
+
+func (m MemoryPermissionModel) GetForUser(_ context.Context, userID int64) (*types.PermissionSet, error) {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	ps, ok := m.Store.perms[userID]
+	if !ok {
+		return types.NewPermissionSet(), nil
+	}
+	return ps.Copy(), nil
+}
+
+func (m MemoryPermissionModel) GetAll(_ context.Context) ([]types.PermCode, error) {
+	return slices.Clone(memoryPermCodes), nil
+}
+
+// AddForUser ignores unknown codes just like the insert that selects them from the permissions table.
+func (m MemoryPermissionModel) AddForUser(_ context.Context, _ int64, userID int64, perms ...types.PermCode) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	if _, ok := m.Store.users[userID]; !ok {
+		return fmt.Errorf("insert or update on table \"user_permissions\" violates foreign key constraint: user %d", userID)
+	}
+	ps := m.Store.grants(userID)
+	for _, pc := range perms {
+		if slices.Contains(memoryPermCodes, pc) {
+			ps.Insert(pc)
+		}
+	}
+	return nil
+}
+
+func (m MemoryPermissionModel) RevokeForUser(_ context.Context, _ int64, userID int64, perms ...types.PermCode) error {
+	m.Store.mutex.Lock()
+	defer m.Store.mutex.Unlock()
+
+	m.Store.grants(userID).RemoveSlice(perms)
+	return nil
+}
+
+func (m MemoryPermissionModel) ReplaceForUser(
+	ctx context.Context,
+	actorID int64,
+	userID int64,
+	perms ...types.PermCode,
+) error {
+	m.Store.mutex.Lock()
+	delete(m.Store.perms, userID)
+	m.Store.mutex.Unlock()
+
+	return m.AddForUser(ctx, actorID, userID, perms...)
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type MFAModel struct {
+	DB  *sql.DB
+	CFG ModelConfig
+}
+
+// Enroll stores a new secret and set of recovery codes for the user. MFA stays disabled until a code generated from
+// the new secret is verified. Any previous recovery codes are discarded. If MFA is already enabled for the user,
+// ErrDuplicateKey is returned.
+func (m MFAModel) Enroll(ctx context.Context, userID int64, secret string, codes []types.MFACode) error {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		insert into user_mfa (user_id, secret)
+		values ($1, $2)
+		on conflict (user_id) do update
+		set secret = excluded.secret, created_at = now(), last_step = 0
+		where user_mfa.enabled = false
+	`
+	result, err := tx.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrDuplicateKey
+	}
+
+	query = `
+		delete from mfa_recovery_codes
+		where user_id = $1
+	`
+	_, err = tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	hashes := make([][]byte, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, types.Hash(string(code.Normalize())))
+	}
+
+	query = `
+		insert into mfa_recovery_codes (user_id, hash)
+		select $1, unnest($2::bytea[])
+	`
+	_, err = tx.ExecContext(ctx, query, userID, pq.Array(hashes))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetForUser returns the user's MFA settings. If the user never enrolled, ErrRecordNotFound is returned.
+func (m MFAModel) GetForUser(ctx context.Context, userID int64) (*types.MFA, error) {
+	query := `
+		select user_id, secret, enabled, last_step
+		from user_mfa
+		where user_id = $1
+	`
+	var mfa types.MFA
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return &mfa, types.MapError(
+		m.DB.QueryRowContext(ctx, query, userID).Scan(
+			&mfa.UserID,
+			&mfa.Secret,
+			&mfa.Enabled,
+			&mfa.LastStep,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+// UseStep records that a TOTP code for the step was accepted. It also enables MFA since the first accepted code is
+// what completes enrollment. If the step isn't newer than the last accepted one, the code is being replayed and
+// ErrInvalidMFACode is returned.
+func (m MFAModel) UseStep(ctx context.Context, userID int64, step int64) error {
+	query := `
+		update user_mfa
+		set last_step = $1, enabled = true
+		where user_id = $2
+		and last_step < $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// UseRecoveryCode consumes one of the user's recovery codes. If the code doesn't match an unused code,
+// ErrInvalidMFACode is returned.
+func (m MFAModel) UseRecoveryCode(ctx context.Context, userID int64, code types.MFACode) error {
+	query := `
+		delete from mfa_recovery_codes
+		where user_id = $1
+		and hash = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, types.Hash(string(code.Normalize())))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrInvalidMFACode
+	}
+
+	return nil
+}
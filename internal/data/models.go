@@ -17,18 +17,34 @@ func NewModelConfig(cfg ...Config) ModelConfig {
 	return mcfg
 }
 
+// Models holds a store for each kind of record. The stores are interfaces so that the handlers don't depend on
+// Postgres. NewModels backs every one of them with the database.
 type Models struct {
-	Company    CompanyModel
-	User       UserModel
-	Token      TokenModel
-	Permission PermissionModel
+	Company      CompanyStore
+	Posting      PostingStore
+	Application  ApplicationStore
+	User         UserStore
+	Token        TokenStore
+	Permission   PermissionStore
+	ApiKey       ApiKeyStore
+	Role         RoleStore
+	MFA          MFAStore
+	LoginFailure LoginFailureStore
+	OIDC         OIDCStore
 }
 
 func NewModels(db *sql.DB, cfg ModelConfig) Models {
 	return Models{
-		Company:    CompanyModel{DB: db, CFG: cfg},
-		User:       UserModel{DB: db, CFG: cfg},
-		Token:      TokenModel{DB: db, CFG: cfg},
-		Permission: PermissionModel{DB: db, CFG: cfg},
+		Company:      CompanyModel{DB: db, CFG: cfg},
+		Posting:      PostingModel{DB: db, CFG: cfg},
+		Application:  ApplicationModel{DB: db, CFG: cfg},
+		User:         UserModel{DB: db, CFG: cfg},
+		Token:        TokenModel{DB: db, CFG: cfg},
+		Permission:   PermissionModel{DB: db, CFG: cfg},
+		ApiKey:       ApiKeyModel{DB: db, CFG: cfg},
+		Role:         RoleModel{DB: db, CFG: cfg},
+		MFA:          MFAModel{DB: db, CFG: cfg},
+		LoginFailure: LoginFailureModel{DB: db, CFG: cfg},
+		OIDC:         OIDCModel{DB: db, CFG: cfg},
 	}
 }
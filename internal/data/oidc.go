@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type OIDCModel struct {
+	DB  *sql.DB
+	CFG ModelConfig
+}
+
+// InsertLogin stores a pending authorization request. Only a hash of the state is kept so that a leaked table can't
+// be used to complete someone else's login.
+func (m OIDCModel) InsertLogin(ctx context.Context, l *types.OIDCLogin) error {
+	query := `
+		insert into oidc_logins (state_hash, code_verifier, nonce, expires_at)
+		values ($1, $2, $3, $4)
+	`
+	args := []any{
+		types.Hash(l.State),
+		l.Verifier,
+		l.Nonce,
+		l.ExpiresAt,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// ConsumeLogin removes the pending authorization request for the state and returns it. Each state can only be used
+// once, and expired requests are never returned.
+func (m OIDCModel) ConsumeLogin(ctx context.Context, state string) (*types.OIDCLogin, error) {
+	query := `
+		delete from oidc_logins
+		where state_hash = $1
+		and expires_at > now()
+		returning code_verifier, nonce, expires_at
+	`
+	l := types.OIDCLogin{State: state}
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return &l, types.MapError(
+		m.DB.QueryRowContext(ctx, query, types.Hash(state)).Scan(
+			&l.Verifier,
+			&l.Nonce,
+			&l.ExpiresAt,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+// GetUserID finds the user that an external identity has been linked to.
+func (m OIDCModel) GetUserID(ctx context.Context, issuer string, subject string) (int64, error) {
+	query := `
+		select user_id
+		from user_identities
+		where issuer = $1 and subject = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	var userID int64
+	return userID, types.MapError(
+		m.DB.QueryRowContext(ctx, query, issuer, subject).Scan(&userID),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+// LinkIdentity links an external identity to the user. Identities are only linked once the provider has verified the
+// email address, so the user is activated as well. The password of a user that wasn't activated yet was chosen by
+// whoever registered the email, who may not own it. That password is replaced with hp, which nobody should know, and
+// the user's tokens and API keys are deleted so that nothing issued before the link keeps working.
+func (m OIDCModel) LinkIdentity(ctx context.Context, userID int64, id *types.Identity, hp types.HashPW) error {
+	slog.Debug("Linking external identity to user", "userID", userID, "issuer", id.Issuer, "subject", id.Subject)
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		insert into user_identities (issuer, subject, user_id)
+		values ($1, $2, $3)
+	`
+	_, err = tx.ExecContext(ctx, query, id.Issuer, id.Subject, userID)
+	if err != nil {
+		return types.MapError(err, types.ErrorMap{".*duplicate key.*": types.ErrDuplicateKey})
+	}
+
+	query = `
+		update users
+		set activated = true, password_hash = $1, updated_at = $2, version = version + 1
+		where id = $3 and not activated
+	`
+	result, err := tx.ExecContext(ctx, query, hp, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected > 0 {
+		for _, query := range []string{
+			"delete from tokens where user_id = $1",
+			"delete from api_keys where user_id = $1",
+		} {
+			_, err = tx.ExecContext(ctx, query, userID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,73 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/pgtest"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// The in-memory stores don't model external identities, so linking is only checked against Postgres.
+func TestLinkIdentity(t *testing.T) {
+	cases := []struct {
+		name          string
+		activated     bool
+		wantOldPW     bool
+		wantTokenKept bool
+	}{
+		{name: "activated user keeps their password and tokens", activated: true, wantOldPW: true, wantTokenKept: true},
+		{name: "unactivated user loses the password and tokens of the registrant"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			models := data.NewModels(pgtest.Open(t), data.ModelConfig{QueryTimeout: 5 * time.Second})
+
+			hp, err := types.NewHashPW(ctx, "registrant-pw")
+			if err != nil {
+				t.Fatalf("Couldn't hash password: %v", err)
+			}
+			u := &types.User{Name: "Owner", Email: "owner@example.com", HashedPassword: hp, Activated: c.activated}
+			err = models.User.Insert(ctx, u)
+			if err != nil {
+				t.Fatalf("Couldn't insert user: %v", err)
+			}
+			tok, err := models.Token.New(ctx, u.ID, time.Hour, types.ScopeActivation)
+			if err != nil {
+				t.Fatalf("Couldn't create token: %v", err)
+			}
+
+			replacement, err := types.NewHashPW(ctx, "replacement-pw")
+			if err != nil {
+				t.Fatalf("Couldn't hash password: %v", err)
+			}
+			id := &types.Identity{Issuer: "https://idp.example.com", Subject: "owner", Email: u.Email}
+			err = models.OIDC.LinkIdentity(ctx, u.ID, id, replacement)
+			if err != nil {
+				t.Fatalf("LinkIdentity() returned an error: %v", err)
+			}
+
+			_, err = models.User.GetForLogin(ctx, types.NewLogin(u.Email, "registrant-pw"))
+			if (err == nil) != c.wantOldPW {
+				t.Errorf("Logging in with the registrant's password returned %v; want success %v", err, c.wantOldPW)
+			}
+			_, err = models.User.GetForLogin(ctx, types.NewLogin(u.Email, "replacement-pw"))
+			if (err == nil) == c.wantOldPW {
+				t.Errorf("Logging in with the replacement password returned %v; want success %v", err, !c.wantOldPW)
+			}
+
+			_, err = models.Token.GetOne(ctx, tok.Plaintext, types.ScopeActivation)
+			if c.wantTokenKept && err != nil {
+				t.Errorf("GetOne() of the existing token returned %v; want it kept", err)
+			}
+			if !c.wantTokenKept && !errors.Is(err, types.ErrRecordNotFound) {
+				t.Errorf("GetOne() of the existing token returned %v; want %v", err, types.ErrRecordNotFound)
+			}
+		})
+	}
+}
@@ -14,16 +14,23 @@ type PermissionModel struct {
 	CFG ModelConfig
 }
 
-func (m PermissionModel) GetForUser(userID int64) (*types.PermissionSet, error) {
+// GetForUser resolves the user's effective permissions. These are the union of the permissions granted directly to the
+// user and the permissions of every role that the user holds.
+func (m PermissionModel) GetForUser(ctx context.Context, userID int64) (*types.PermissionSet, error) {
 	query := `
 		select permissions.code
 		from permissions
 		join user_permissions on permissions.id = user_permissions.permission_id
-		join users on users.id = user_permissions.user_id
-		where users.id = $1
+		where user_permissions.user_id = $1
+		union
+		select permissions.code
+		from permissions
+		join role_permissions on permissions.id = role_permissions.permission_id
+		join user_roles on user_roles.role_id = role_permissions.role_id
+		where user_roles.user_id = $1
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, userID)
@@ -47,20 +54,154 @@ func (m PermissionModel) GetForUser(userID int64) (*types.PermissionSet, error)
 	return permissions, nil
 }
 
-func (m PermissionModel) AddForUser(userID int64, perms ...types.PermCode) error {
-	slog.Debug("Inserting permissions for user", "userID", userID, "perms", perms)
+// actorArg converts the ID of the user making a change into a value for a nullable actor column. Changes made by the
+// system have no acting user.
+func actorArg(actorID int64) any {
+	if actorID == types.SystemUserID {
+		return nil
+	}
+	return actorID
+}
+
+// GetAll lists every permission code that can be granted.
+func (m PermissionModel) GetAll(ctx context.Context) ([]types.PermCode, error) {
 	query := `
-		insert into user_permissions (
-			user_id, permission_id
-		)
-		select $1, permissions.id
+		select distinct code
 		from permissions
-		where permissions.code = any($2)
+		order by code
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := make([]types.PermCode, 0, 10)
+	for rows.Next() {
+		var pc types.PermCode
+		err := rows.Scan(&pc)
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, pc)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return perms, nil
+}
+
+// AddForUser grants the permissions to the user. Only the grants that didn't already exist are recorded in the
+// audit table.
+func (m PermissionModel) AddForUser(ctx context.Context, actorID int64, userID int64, perms ...types.PermCode) error {
+	slog.Debug("Inserting permissions for user", "actorID", actorID, "userID", userID, "perms", perms)
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return grantPermissions(ctx, m.DB, actorID, userID, perms)
+}
+
+// RevokeForUser removes the permissions from the user. Only the grants that actually existed are recorded in the
+// audit table.
+func (m PermissionModel) RevokeForUser(
+	ctx context.Context,
+	actorID int64,
+	userID int64,
+	perms ...types.PermCode,
+) error {
+	slog.Debug("Revoking permissions for user", "actorID", actorID, "userID", userID, "perms", perms)
+	query := `
+		with revoked as (
+			delete from user_permissions
+			using permissions
+			where user_permissions.permission_id = permissions.id
+			and user_permissions.user_id = $1
+			and permissions.code = any($2)
+			returning permissions.code
+		)
+		insert into permission_audit (actor_id, user_id, action, code)
+		select $3::bigint, $1::bigint, 'revoke', revoked.code
+		from revoked
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(perms))
+	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(perms), actorArg(actorID))
+	return err
+}
+
+// ReplaceForUser makes the provided permissions the user's complete set. Both the revocations and the grants that
+// this requires are recorded in the audit table.
+func (m PermissionModel) ReplaceForUser(
+	ctx context.Context,
+	actorID int64,
+	userID int64,
+	perms ...types.PermCode,
+) error {
+	slog.Debug("Replacing permissions for user", "actorID", actorID, "userID", userID, "perms", perms)
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		with revoked as (
+			delete from user_permissions
+			using permissions
+			where user_permissions.permission_id = permissions.id
+			and user_permissions.user_id = $1
+			and permissions.code <> all($2)
+			returning permissions.code
+		)
+		insert into permission_audit (actor_id, user_id, action, code)
+		select $3::bigint, $1::bigint, 'revoke', revoked.code
+		from revoked
+	`
+	_, err = tx.ExecContext(ctx, query, userID, pq.Array(perms), actorArg(actorID))
+	if err != nil {
+		return err
+	}
+
+	err = grantPermissions(ctx, tx, actorID, userID, perms)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func grantPermissions(ctx context.Context, e execer, actorID int64, userID int64, perms []types.PermCode) error {
+	query := `
+		with granted as (
+			insert into user_permissions (user_id, permission_id)
+			select $1::bigint, permissions.id
+			from permissions
+			where permissions.code = any($2)
+			on conflict do nothing
+			returning permission_id
+		)
+		insert into permission_audit (actor_id, user_id, action, code)
+		select $3::bigint, $1::bigint, 'grant', permissions.code
+		from granted
+		join permissions on permissions.id = granted.permission_id
+	`
+
+	_, err := e.ExecContext(ctx, query, userID, pq.Array(perms), actorArg(actorID))
 	return err
 }
@@ -0,0 +1,121 @@
+package data_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/pgtest"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func sortedPerms(ps *types.PermissionSet) []types.PermCode {
+	return slices.Sorted(ps.Items())
+}
+
+func TestPermissionGrants(t *testing.T) {
+	eachStore(t, func(t *testing.T, models data.Models) {
+		ctx := context.Background()
+		admin := insertUser(t, models, "Admin", "admin@example.com")
+		user := insertUser(t, models, "User", "user@example.com")
+
+		steps := []struct {
+			name   string
+			change func() error
+			want   []types.PermCode
+		}{
+			{
+				name: "add ignores unknown codes",
+				change: func() error {
+					return models.Permission.AddForUser(ctx, admin.ID, user.ID, types.CompanyRead, "bogus:code")
+				},
+				want: []types.PermCode{types.CompanyRead},
+			},
+			{
+				name: "add keeps existing grants",
+				change: func() error {
+					return models.Permission.AddForUser(ctx, admin.ID, user.ID, types.CompanyRead, types.CompanyWrite)
+				},
+				want: []types.PermCode{types.CompanyRead, types.CompanyWrite},
+			},
+			{
+				name: "revoke",
+				change: func() error {
+					return models.Permission.RevokeForUser(ctx, admin.ID, user.ID, types.CompanyRead, types.UserRead)
+				},
+				want: []types.PermCode{types.CompanyWrite},
+			},
+			{
+				name: "replace",
+				change: func() error {
+					return models.Permission.ReplaceForUser(ctx, admin.ID, user.ID, types.PostingRead, types.UserRead)
+				},
+				want: []types.PermCode{types.PostingRead, types.UserRead},
+			},
+			{
+				name: "replace with nothing",
+				change: func() error {
+					return models.Permission.ReplaceForUser(ctx, types.SystemUserID, user.ID)
+				},
+				want: []types.PermCode{},
+			},
+		}
+		for _, s := range steps {
+			err := s.change()
+			if err != nil {
+				t.Fatalf("%s: returned an error: %v", s.name, err)
+			}
+			ps, err := models.Permission.GetForUser(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("%s: GetForUser() returned an error: %v", s.name, err)
+			}
+			got := sortedPerms(ps)
+			if !slices.Equal(got, s.want) {
+				t.Errorf("%s: GetForUser() = %v; want %v", s.name, got, s.want)
+			}
+		}
+	})
+}
+
+// The in-memory stores don't model roles, so the union with role permissions is only checked against Postgres.
+func TestPermissionRoles(t *testing.T) {
+	ctx := context.Background()
+	models := data.NewModels(pgtest.Open(t), data.ModelConfig{QueryTimeout: 5 * time.Second})
+	user := insertUser(t, models, "User", "user@example.com")
+
+	err := models.Permission.AddForUser(ctx, types.SystemUserID, user.ID, types.CompanyRead, types.UserRead)
+	if err != nil {
+		t.Fatalf("AddForUser() returned an error: %v", err)
+	}
+	err = models.Role.AddForUser(ctx, types.SystemUserID, user.ID, "viewer")
+	if err != nil {
+		t.Fatalf("Role.AddForUser() returned an error: %v", err)
+	}
+
+	ps, err := models.Permission.GetForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetForUser() returned an error: %v", err)
+	}
+	got := sortedPerms(ps)
+	want := []types.PermCode{types.ApplicationRead, types.CompanyRead, types.PostingRead, types.UserRead}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetForUser() = %v; want %v", got, want)
+	}
+
+	// Revoking a direct grant doesn't take away the same permission from a role.
+	err = models.Permission.RevokeForUser(ctx, types.SystemUserID, user.ID, types.CompanyRead, types.UserRead)
+	if err != nil {
+		t.Fatalf("RevokeForUser() returned an error: %v", err)
+	}
+	ps, err = models.Permission.GetForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetForUser() returned an error: %v", err)
+	}
+	got = sortedPerms(ps)
+	want = []types.PermCode{types.ApplicationRead, types.CompanyRead, types.PostingRead}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetForUser() after revoking = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,441 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type PostingModel struct {
+	DB  *sql.DB
+	CFG ModelConfig
+}
+
+var PostingSearchFields = NewSearchFields("title", "location", "remote")
+var PostingSortFields = NewSortFields(
+	"id",
+	"created_at",
+	"updated_at",
+	"title",
+	"posted_at",
+	"closed_at",
+	"salary_min",
+	"salary_max",
+)
+
+func (m PostingModel) GetVersion(ctx context.Context, t types.Tenant, id int64) (int64, error) {
+	query := `
+		select version
+		from postings
+		where id = $1
+		and (owner_id = $2 or $3)
+	`
+	var version int64
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return version, types.MapError(
+		m.DB.QueryRowContext(ctx, query, id, t.UserID, t.All).Scan(&version),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+// Insert only succeeds if the posting's company is visible to the tenant. Otherwise, ErrRecordNotFound is returned.
+func (m PostingModel) Insert(ctx context.Context, t types.Tenant, posting *types.Posting) error {
+	query := `
+		insert into postings (
+			owner_id, company_id, title, url, location, remote, salary_min, salary_max, posted_at, closed_at
+		)
+		select
+			$1::bigint,
+			companies.id,
+			$3::text,
+			$4::text,
+			$5::text,
+			$6::text,
+			$7::bigint,
+			$8::bigint,
+			$9::timestamptz,
+			$10::timestamptz
+		from companies
+		where companies.id = $2
+		and (companies.owner_id = $11 or companies.visibility = 'public' or $12)
+		returning id, created_at, updated_at, coalesce(owner_id, 0), version
+	`
+	args := []any{
+		ownerArg(t),
+		posting.CompanyID,
+		posting.Title,
+		posting.URL,
+		posting.Location,
+		posting.Remote,
+		posting.SalaryMin,
+		posting.SalaryMax,
+		posting.PostedAt,
+		posting.ClosedAt,
+		t.UserID,
+		t.All,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return types.MapError(
+		m.DB.QueryRowContext(ctx, query, args...).Scan(
+			&posting.ID,
+			&posting.CreatedAt,
+			&posting.UpdatedAt,
+			&posting.OwnerID,
+			&posting.Version,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+func (m PostingModel) GetOne(ctx context.Context, t types.Tenant, id int64) (*types.Posting, error) {
+	query := `
+		select
+			id,
+			created_at,
+			updated_at,
+			coalesce(owner_id, 0),
+			company_id,
+			title,
+			url,
+			location,
+			remote,
+			salary_min,
+			salary_max,
+			posted_at,
+			closed_at,
+			version
+		from postings
+		where id = $1
+		and (owner_id = $2 or $3)
+	`
+	var p types.Posting
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return &p, types.MapError(
+		m.DB.QueryRowContext(ctx, query, id, t.UserID, t.All).Scan(
+			&p.ID,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&p.OwnerID,
+			&p.CompanyID,
+			&p.Title,
+			&p.URL,
+			&p.Location,
+			&p.Remote,
+			&p.SalaryMin,
+			&p.SalaryMax,
+			&p.PostedAt,
+			&p.ClosedAt,
+			&p.Version,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+// GetMany fetches postings matching the filters. If a companyID is provided, only that company's postings are
+// included.
+func (m PostingModel) GetMany(
+	ctx context.Context,
+	t types.Tenant,
+	f Filters,
+	companyID ...int64,
+) ([]*types.Posting, *ListMetadata, error) {
+	args := []any{}
+	query_parts := []string{`
+		select
+			count(*) over (),
+			id,
+			created_at,
+			updated_at,
+			coalesce(owner_id, 0),
+			company_id,
+			title,
+			url,
+			location,
+			remote,
+			salary_min,
+			salary_max,
+			posted_at,
+			closed_at,
+			version
+		from postings
+	`}
+
+	where_parts := []string{}
+
+	if !t.All {
+		args = append(args, t.UserID)
+		where_parts = append(where_parts, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+
+	if len(companyID) > 0 {
+		args = append(args, companyID[0])
+		where_parts = append(where_parts, fmt.Sprintf("company_id = $%d", len(args)))
+	}
+
+	if f.Search != nil {
+		for k, v := range *f.Search {
+			args = append(args, v)
+			where_parts = append(where_parts, fmt.Sprintf("%s ~* $%d", k, len(args)))
+		}
+	}
+
+	if len(where_parts) > 0 {
+		query_parts = append(query_parts, "where", strings.Join(where_parts, " and "))
+	}
+
+	sort_parts := []string{}
+
+	if f.Sort != nil {
+		for k, v := range f.Sort.FromOldest() {
+			sort_parts = append(sort_parts, fmt.Sprintf("%s %s", k, v))
+		}
+	}
+
+	if len(sort_parts) > 0 {
+		query_parts = append(query_parts, "order by", strings.Join(sort_parts, ", "))
+	}
+
+	if f.Page != nil && f.PageSize != nil {
+		args = append(args, *f.PageSize, (*f.Page-1)**f.PageSize)
+		query_parts = append(query_parts, fmt.Sprintf("limit $%d offset $%d", len(args)-1, len(args)))
+	}
+
+	query := strings.Join(query_parts, " ")
+
+	slog.Debug("Assembled GetMany query", "query", query, "args", args)
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var recordCount int
+	postings := make([]*types.Posting, 0, 10)
+	for rows.Next() {
+		var p types.Posting
+		err := rows.Scan(
+			&recordCount,
+			&p.ID,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&p.OwnerID,
+			&p.CompanyID,
+			&p.Title,
+			&p.URL,
+			&p.Location,
+			&p.Remote,
+			&p.SalaryMin,
+			&p.SalaryMax,
+			&p.PostedAt,
+			&p.ClosedAt,
+			&p.Version,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		postings = append(postings, &p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metadata := NewListMetadata(f, recordCount)
+	return postings, &metadata, nil
+}
+
+func (m PostingModel) Update(ctx context.Context, t types.Tenant, posting *types.Posting) error {
+	query := `
+		update postings
+		set
+			title = $1,
+			url = $2,
+			location = $3,
+			remote = $4,
+			salary_min = $5,
+			salary_max = $6,
+			posted_at = $7,
+			closed_at = $8,
+			updated_at = $9,
+			version = version + 1
+		where id = $10 and version = $11
+		and (owner_id = $12 or $13)
+		returning updated_at, version
+	`
+	args := []any{
+		posting.Title,
+		posting.URL,
+		posting.Location,
+		posting.Remote,
+		posting.SalaryMin,
+		posting.SalaryMax,
+		posting.PostedAt,
+		posting.ClosedAt,
+		time.Now(),
+		posting.ID,
+		posting.Version,
+		t.UserID,
+		t.All,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return types.MapError(
+		m.DB.QueryRowContext(ctx, query, args...).Scan(&posting.UpdatedAt, &posting.Version),
+		types.ErrorMap{sql.ErrNoRows: types.ErrEditConflict},
+	)
+}
+
+func (m PostingModel) PartialUpdate(
+	ctx context.Context,
+	t types.Tenant,
+	id int64,
+	version int64,
+	partial *types.PartialPosting,
+) (*types.Posting, error) {
+	query := `
+		update postings
+		set updated_at = $1, version = version + 1
+	`
+	args := []any{
+		time.Now(),
+	}
+
+	i := 2
+	if partial.Title != nil {
+		query += fmt.Sprintf(", title = $%d", i)
+		args = append(args, *partial.Title)
+		i += 1
+	}
+
+	if partial.URL != nil {
+		query += fmt.Sprintf(", url = $%d", i)
+		args = append(args, *partial.URL)
+		i += 1
+	}
+
+	if partial.Location != nil {
+		query += fmt.Sprintf(", location = $%d", i)
+		args = append(args, *partial.Location)
+		i += 1
+	}
+
+	if partial.Remote != nil {
+		query += fmt.Sprintf(", remote = $%d", i)
+		args = append(args, *partial.Remote)
+		i += 1
+	}
+
+	if partial.SalaryMin != nil {
+		query += fmt.Sprintf(", salary_min = $%d", i)
+		args = append(args, *partial.SalaryMin)
+		i += 1
+	}
+
+	if partial.SalaryMax != nil {
+		query += fmt.Sprintf(", salary_max = $%d", i)
+		args = append(args, *partial.SalaryMax)
+		i += 1
+	}
+
+	if partial.PostedAt != nil {
+		query += fmt.Sprintf(", posted_at = $%d", i)
+		args = append(args, *partial.PostedAt)
+		i += 1
+	}
+
+	if partial.ClosedAt != nil {
+		query += fmt.Sprintf(", closed_at = $%d", i)
+		args = append(args, *partial.ClosedAt)
+		i += 1
+	}
+
+	query += fmt.Sprintf(`
+		where id = $%d and version = $%d
+		and (owner_id = $%d or $%d)
+		returning
+			created_at,
+			updated_at,
+			coalesce(owner_id, 0),
+			company_id,
+			title,
+			url,
+			location,
+			remote,
+			salary_min,
+			salary_max,
+			posted_at,
+			closed_at,
+			version
+	`, i, i+1, i+2, i+3)
+	args = append(args, id, version, t.UserID, t.All)
+	p := &types.Posting{
+		ID: id,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return p, types.MapError(
+		m.DB.QueryRowContext(ctx, query, args...).Scan(
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&p.OwnerID,
+			&p.CompanyID,
+			&p.Title,
+			&p.URL,
+			&p.Location,
+			&p.Remote,
+			&p.SalaryMin,
+			&p.SalaryMax,
+			&p.PostedAt,
+			&p.ClosedAt,
+			&p.Version,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrEditConflict},
+	)
+}
+
+func (m PostingModel) Delete(ctx context.Context, t types.Tenant, id int64) error {
+	query := `
+		delete from postings
+		where id = $1
+		and (owner_id = $2 or $3)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, t.UserID, t.All)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrRecordNotFound
+	}
+
+	return nil
+}
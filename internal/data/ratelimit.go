@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// LimitPolicy is a named limit on how often a client may do something. The name keeps the state of different policies
+// apart when they share a store.
+type LimitPolicy struct {
+	Name  string
+	Limit rate.Limit
+	Burst int
+}
+
+// RefillTime is how long it takes for a client that used up its whole burst to get all of it back.
+func (p LimitPolicy) RefillTime() time.Duration {
+	return time.Duration(float64(p.Burst) / float64(p.Limit) * float64(time.Second))
+}
+
+// LimitResult describes a rate limit decision in the terms of the RateLimit-* response headers.
+type LimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimiter decides whether the client identified by a key may do something right now.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (*LimitResult, error)
+}
+
+const (
+	LimitStoreMemory = "memory"
+	LimitStoreRedis  = "redis"
+)
+
+// NewRateLimiter builds a limiter that enforces the policy with the configured store.
+func NewRateLimiter(cfg Config, rdb *redis.Client, p LimitPolicy) RateLimiter {
+	if cfg.LimitStore == LimitStoreRedis {
+		return NewRedisLimiter(rdb, cfg, p)
+	}
+	cl := NewPolicyClientMap(cfg, p)
+	go cl.CleanCycle()
+	return cl
+}
+
+// gcraScript implements the generic cell rate algorithm. Instead of counting tokens, it stores the theoretical arrival
+// time (TAT) of the next request. A request is allowed if it doesn't arrive earlier than the TAT minus the burst
+// tolerance. Redis's clock is used so that every replica agrees on the time. All times are in microseconds.
+var gcraScript = redis.NewScript(`
+	local interval = tonumber(ARGV[1])
+	local tolerance = interval * tonumber(ARGV[2])
+
+	local t = redis.call('TIME')
+	local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+	local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+	if tat < now then
+		tat = now
+	end
+
+	local next_tat = tat + interval
+	local allow_at = next_tat - tolerance
+	if allow_at > now then
+		return {0, 0, tat - now, allow_at - now}
+	end
+
+	redis.call('SET', KEYS[1], string.format('%d', next_tat), 'PX', math.ceil((next_tat - now) / 1000))
+	local remaining = math.floor((now - allow_at) / interval)
+	return {1, remaining, next_tat - now, 0}
+`)
+
+// RedisLimiter keeps its state in Redis so that every replica of the API shares the same limits.
+type RedisLimiter struct {
+	Client   *redis.Client
+	Prefix   string
+	Interval time.Duration
+	Burst    int
+	Timeout  time.Duration
+}
+
+func NewRedisLimiter(rdb *redis.Client, cfg Config, p LimitPolicy) *RedisLimiter {
+	return &RedisLimiter{
+		Client:   rdb,
+		Prefix:   fmt.Sprintf("the-hunt:limit:%s:", p.Name),
+		Interval: time.Duration(math.Round(float64(time.Second) / float64(p.Limit))),
+		Burst:    p.Burst,
+		Timeout:  cfg.RedisTimeout,
+	}
+}
+
+func (rl *RedisLimiter) Allow(ctx context.Context, key string) (*LimitResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, rl.Timeout)
+	defer cancel()
+
+	nums, err := gcraScript.Run(
+		ctx,
+		rl.Client,
+		[]string{rl.Prefix + key},
+		rl.Interval.Microseconds(),
+		rl.Burst,
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", nums)
+	}
+
+	return &LimitResult{
+		Allowed:    nums[0] == 1,
+		Limit:      rl.Burst,
+		Remaining:  int(nums[1]),
+		ResetAfter: time.Duration(nums[2]) * time.Microsecond,
+		RetryAfter: time.Duration(nums[3]) * time.Microsecond,
+	}, nil
+}
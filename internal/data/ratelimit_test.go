@@ -0,0 +1,97 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	start := time.Unix(1_700_000_000, 0)
+	cfg := data.Config{RedisTimeout: time.Second}
+	rl := data.NewRedisLimiter(rdb, cfg, data.LimitPolicy{Name: "test", Limit: rate.Limit(2), Burst: 3})
+
+	ms := time.Millisecond
+	cases := []struct {
+		name    string
+		key     string
+		elapsed time.Duration
+		want    data.LimitResult
+	}{
+		{name: "burst 1", key: "a", want: data.LimitResult{Allowed: true, Remaining: 2, ResetAfter: 500 * ms}},
+		{name: "burst 2", key: "a", want: data.LimitResult{Allowed: true, Remaining: 1, ResetAfter: 1000 * ms}},
+		{name: "burst 3", key: "a", want: data.LimitResult{Allowed: true, Remaining: 0, ResetAfter: 1500 * ms}},
+		{
+			name: "burst exhausted",
+			key:  "a",
+			want: data.LimitResult{Allowed: false, Remaining: 0, ResetAfter: 1500 * ms, RetryAfter: 500 * ms},
+		},
+		{
+			name: "other key has its own limit",
+			key:  "b",
+			want: data.LimitResult{Allowed: true, Remaining: 2, ResetAfter: 500 * ms},
+		},
+		{
+			name:    "not refilled yet",
+			key:     "a",
+			elapsed: 400 * ms,
+			want:    data.LimitResult{Allowed: false, Remaining: 0, ResetAfter: 1100 * ms, RetryAfter: 100 * ms},
+		},
+		{
+			name:    "refilled by one",
+			key:     "a",
+			elapsed: 500 * ms,
+			want:    data.LimitResult{Allowed: true, Remaining: 0, ResetAfter: 1500 * ms},
+		},
+		{
+			name:    "only refilled by one",
+			key:     "a",
+			elapsed: 500 * ms,
+			want:    data.LimitResult{Allowed: false, Remaining: 0, ResetAfter: 1500 * ms, RetryAfter: 500 * ms},
+		},
+		{
+			name:    "fully refilled",
+			key:     "a",
+			elapsed: 10 * time.Second,
+			want:    data.LimitResult{Allowed: true, Remaining: 2, ResetAfter: 500 * ms},
+		},
+	}
+	for _, c := range cases {
+		mr.SetTime(start.Add(c.elapsed))
+		got, err := rl.Allow(context.Background(), c.key)
+		if err != nil {
+			t.Fatalf("%s: Allow() returned an error: %v", c.name, err)
+		}
+		c.want.Limit = 3
+		if *got != c.want {
+			t.Errorf("%s: Allow() = %+v; want %+v", c.name, *got, c.want)
+		}
+	}
+}
+
+func TestRedisLimiterUnavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	mr.Close()
+
+	rl := data.NewRedisLimiter(
+		rdb,
+		data.Config{RedisTimeout: time.Second},
+		data.LimitPolicy{Name: "test", Limit: rate.Limit(1), Burst: 1},
+	)
+	_, err := rl.Allow(context.Background(), "a")
+	if err == nil {
+		t.Errorf("Allow() did not return an error")
+	}
+}
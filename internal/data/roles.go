@@ -0,0 +1,160 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/lib/pq"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type RoleModel struct {
+	DB  *sql.DB
+	CFG ModelConfig
+}
+
+// GetAll lists every role along with the permissions that it bundles.
+func (m RoleModel) GetAll(ctx context.Context) ([]*types.Role, error) {
+	query := `
+		select
+			roles.id,
+			roles.name,
+			array_remove(array_agg(permissions.code order by permissions.code), null)
+		from roles
+		left join role_permissions on role_permissions.role_id = roles.id
+		left join permissions on permissions.id = role_permissions.permission_id
+		group by roles.id
+		order by roles.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]*types.Role, 0, 4)
+	for rows.Next() {
+		var role types.Role
+		var perms []string
+		err := rows.Scan(&role.ID, &role.Name, pq.Array(&perms))
+		if err != nil {
+			return nil, err
+		}
+		role.Permissions = permCodes(perms)
+		roles = append(roles, &role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (m RoleModel) GetForUser(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+		select roles.name
+		from roles
+		join user_roles on user_roles.role_id = roles.id
+		where user_roles.user_id = $1
+		order by roles.name
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0, 4)
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// AddForUser assigns the roles to the user. Only the assignments that didn't already exist are recorded in the audit
+// table.
+func (m RoleModel) AddForUser(ctx context.Context, actorID int64, userID int64, names ...string) error {
+	slog.Debug("Assigning roles to user", "actorID", actorID, "userID", userID, "roles", names)
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return assignRoles(ctx, m.DB, actorID, userID, names)
+}
+
+// ReplaceForUser makes the provided roles the user's complete set of roles. Both the removals and the assignments
+// that this requires are recorded in the audit table.
+func (m RoleModel) ReplaceForUser(ctx context.Context, actorID int64, userID int64, names ...string) error {
+	slog.Debug("Replacing roles for user", "actorID", actorID, "userID", userID, "roles", names)
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		with removed as (
+			delete from user_roles
+			using roles
+			where user_roles.role_id = roles.id
+			and user_roles.user_id = $1
+			and roles.name <> all($2)
+			returning roles.name
+		)
+		insert into permission_audit (actor_id, user_id, action, role)
+		select $3::bigint, $1::bigint, 'unassign', removed.name
+		from removed
+	`
+	_, err = tx.ExecContext(ctx, query, userID, pq.Array(names), actorArg(actorID))
+	if err != nil {
+		return err
+	}
+
+	err = assignRoles(ctx, tx, actorID, userID, names)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func assignRoles(ctx context.Context, e execer, actorID int64, userID int64, names []string) error {
+	query := `
+		with assigned as (
+			insert into user_roles (user_id, role_id)
+			select $1::bigint, roles.id
+			from roles
+			where roles.name = any($2)
+			on conflict do nothing
+			returning role_id
+		)
+		insert into permission_audit (actor_id, user_id, action, role)
+		select $3::bigint, $1::bigint, 'assign', roles.name
+		from assigned
+		join roles on roles.id = assigned.role_id
+	`
+
+	_, err := e.ExecContext(ctx, query, userID, pq.Array(names), actorArg(actorID))
+	return err
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+type CompanyStore interface {
+	GetVersion(ctx context.Context, t types.Tenant, id int64) (int64, error)
+	Insert(ctx context.Context, t types.Tenant, company *types.Company) error
+	GetOne(ctx context.Context, t types.Tenant, id int64) (*types.Company, error)
+	GetMany(ctx context.Context, t types.Tenant, f Filters) ([]*types.Company, *ListMetadata, error)
+	Update(ctx context.Context, t types.Tenant, company *types.Company) error
+	PartialUpdate(
+		ctx context.Context,
+		t types.Tenant,
+		id int64,
+		version int64,
+		partial *types.PartialCompany,
+	) (*types.Company, error)
+	Delete(ctx context.Context, t types.Tenant, id int64) error
+}
+
+type PostingStore interface {
+	GetVersion(ctx context.Context, t types.Tenant, id int64) (int64, error)
+	Insert(ctx context.Context, t types.Tenant, posting *types.Posting) error
+	GetOne(ctx context.Context, t types.Tenant, id int64) (*types.Posting, error)
+	GetMany(ctx context.Context, t types.Tenant, f Filters, companyID ...int64) ([]*types.Posting, *ListMetadata, error)
+	Update(ctx context.Context, t types.Tenant, posting *types.Posting) error
+	PartialUpdate(
+		ctx context.Context,
+		t types.Tenant,
+		id int64,
+		version int64,
+		partial *types.PartialPosting,
+	) (*types.Posting, error)
+	Delete(ctx context.Context, t types.Tenant, id int64) error
+}
+
+type ApplicationStore interface {
+	Insert(ctx context.Context, t types.Tenant, application *types.Application) error
+	GetOne(ctx context.Context, t types.Tenant, id int64) (*types.Application, error)
+	GetMany(ctx context.Context, t types.Tenant, f Filters) ([]*types.Application, *ListMetadata, error)
+	Transition(
+		ctx context.Context,
+		t types.Tenant,
+		application *types.Application,
+		transition *types.ApplicationTransition,
+	) error
+	GetTransitions(ctx context.Context, t types.Tenant, id int64) ([]*types.ApplicationTransition, error)
+	Delete(ctx context.Context, t types.Tenant, id int64) error
+}
+
+type UserStore interface {
+	GetVersion(ctx context.Context, id int64) (int64, error)
+	Insert(ctx context.Context, user *types.User) error
+	GetOne(ctx context.Context, id int64) (*types.User, error)
+	GetForLogin(ctx context.Context, l *types.Login) (*types.User, error)
+	GetByEmail(ctx context.Context, email types.Email) (*types.User, error)
+	GetForToken(ctx context.Context, t types.Token) (*types.User, error)
+	GetMany(ctx context.Context, f Filters) ([]*types.User, *ListMetadata, error)
+	Update(ctx context.Context, user *types.User) error
+	Activate(ctx context.Context, token types.Token) (int64, error)
+	DeleteTokensForUser(ctx context.Context, userID int64, scope string) error
+	PartialUpdate(ctx context.Context, id int64, version int64, partial *types.PartialUser) (*types.User, error)
+	Delete(ctx context.Context, id int64) error
+	SetAdmin(ctx context.Context, id int64) error
+}
+
+type TokenStore interface {
+	New(ctx context.Context, userID int64, ttl time.Duration, scope types.TokenScope) (*types.Token, error)
+	Insert(ctx context.Context, t *types.Token) error
+	InsertFamily(ctx context.Context, tokens ...*types.Token) error
+	Rotate(ctx context.Context, pt types.PlainToken, access *types.Token, refresh *types.Token) error
+	GetOne(ctx context.Context, pt types.PlainToken, scope types.TokenScope) (*types.Token, error)
+	DeleteForUser(ctx context.Context, scope string, userID int64) error
+	Touch(ctx context.Context, id int64) error
+	GetSessionsForUser(ctx context.Context, userID int64) ([]*types.Session, error)
+	DeleteSession(ctx context.Context, userID int64, id int64) error
+	GetAllForUser(ctx context.Context, userID int64) ([]*types.TokenInfo, error)
+	Delete(ctx context.Context, id int64) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type PermissionStore interface {
+	GetForUser(ctx context.Context, userID int64) (*types.PermissionSet, error)
+	GetAll(ctx context.Context) ([]types.PermCode, error)
+	AddForUser(ctx context.Context, actorID int64, userID int64, perms ...types.PermCode) error
+	RevokeForUser(ctx context.Context, actorID int64, userID int64, perms ...types.PermCode) error
+	ReplaceForUser(ctx context.Context, actorID int64, userID int64, perms ...types.PermCode) error
+}
+
+type ApiKeyStore interface {
+	Insert(ctx context.Context, k *types.ApiKey) error
+	GetOne(ctx context.Context, pt types.PlainToken) (*types.ApiKey, error)
+	GetForUser(ctx context.Context, userID int64) ([]*types.ApiKey, error)
+	Touch(ctx context.Context, id int64) error
+	Delete(ctx context.Context, userID int64, id int64) error
+	DeleteForUser(ctx context.Context, userID int64) error
+}
+
+type RoleStore interface {
+	GetAll(ctx context.Context) ([]*types.Role, error)
+	GetForUser(ctx context.Context, userID int64) ([]string, error)
+	AddForUser(ctx context.Context, actorID int64, userID int64, names ...string) error
+	ReplaceForUser(ctx context.Context, actorID int64, userID int64, names ...string) error
+}
+
+type MFAStore interface {
+	Enroll(ctx context.Context, userID int64, secret string, codes []types.MFACode) error
+	GetForUser(ctx context.Context, userID int64) (*types.MFA, error)
+	UseStep(ctx context.Context, userID int64, step int64) error
+	UseRecoveryCode(ctx context.Context, userID int64, code types.MFACode) error
+}
+
+type LoginFailureStore interface {
+	LockedUntil(ctx context.Context, email string, ip string) (time.Time, error)
+	RecordFailure(
+		ctx context.Context,
+		kind types.LoginFailureKind,
+		subject string,
+		policy types.LockoutPolicy,
+	) (int, error)
+	Reset(ctx context.Context, kind types.LoginFailureKind, subject string) error
+}
+
+type OIDCStore interface {
+	InsertLogin(ctx context.Context, l *types.OIDCLogin) error
+	ConsumeLogin(ctx context.Context, state string) (*types.OIDCLogin, error)
+	GetUserID(ctx context.Context, issuer string, subject string) (int64, error)
+	LinkIdentity(ctx context.Context, userID int64, id *types.Identity, hp types.HashPW) error
+}
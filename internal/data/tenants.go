@@ -0,0 +1,14 @@
+package data
+
+import (
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// ownerArg maps the tenant to the value stored in an owner_id column. Rows created by the system rather than by a
+// user are left without an owner.
+func ownerArg(t types.Tenant) any {
+	if t.UserID == types.SystemUserID {
+		return nil
+	}
+	return t.UserID
+}
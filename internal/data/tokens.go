@@ -13,44 +13,163 @@ type TokenModel struct {
 	CFG ModelConfig
 }
 
-func (m TokenModel) New(userID int64, ttl time.Duration, scope types.TokenScope, isAdmin bool) (*types.Token, error) {
-	token := types.GenerateToken(userID, ttl, scope, isAdmin)
-	err := m.Insert(token)
+func (m TokenModel) New(
+	ctx context.Context,
+	userID int64,
+	ttl time.Duration,
+	scope types.TokenScope,
+) (*types.Token, error) {
+	token := types.GenerateToken(userID, ttl, scope)
+	err := m.Insert(ctx, token)
 	return token, err
 }
 
-func (m TokenModel) Insert(t *types.Token) error {
-	var id any
-	if !t.IsAdmin {
-		id = any(t.UserID)
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx so that tokens can be inserted inside or outside of a
+// transaction.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertToken(ctx context.Context, q rowQuerier, t *types.Token) error {
+	var family any
+	if t.Family != "" {
+		family = any(t.Family)
 	}
 
 	query := `
-		insert into tokens (hash, user_id, expires_at, scope, is_admin)
-		values ($1, $2, $3, $4, $5)
+		insert into tokens (hash, user_id, expires_at, scope, ip, user_agent, family)
+		values ($1, $2, $3, $4, $5, $6, $7)
+		returning id
 	`
 	args := []any{
 		t.Hash,
-		id,
+		t.UserID,
 		t.ExpiresAt,
 		t.Scope,
-		t.IsAdmin,
+		t.IP,
+		t.UserAgent,
+		family,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
-	defer cancel()
-
-	_, err := m.DB.ExecContext(
+	return q.QueryRowContext(
 		ctx,
 		query,
 		args...,
+	).Scan(&t.ID)
+}
+
+func (m TokenModel) Insert(ctx context.Context, t *types.Token) error {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return insertToken(ctx, m.DB, t)
+}
+
+// InsertFamily adds all of the tokens in a single transaction so that a token family is never left half-created.
+func (m TokenModel) InsertFamily(ctx context.Context, tokens ...*types.Token) error {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range tokens {
+		err = insertToken(ctx, tx, t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Rotate exchanges a refresh token for the provided access and refresh tokens. The new tokens inherit the user and
+// family of the presented token, and the presented token is marked as rotated so that it can't be used again. If a
+// token that was already rotated is presented, it has probably been stolen, so the whole family is revoked and
+// ErrTokenReused is returned.
+func (m TokenModel) Rotate(ctx context.Context, pt types.PlainToken, access *types.Token, refresh *types.Token) error {
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		select id, user_id, family, rotated_at
+		from tokens
+		where hash = $1
+		and scope = $2
+		and expires_at > $3
+		for update
+	`
+	args := []any{
+		types.Hash(string(pt)),
+		types.ScopeRefresh,
+		time.Now(),
+	}
+
+	var old types.Token
+	err = types.MapError(
+		tx.QueryRowContext(ctx, query, args...).Scan(
+			&old.ID,
+			&old.UserID,
+			&old.Family,
+			&old.RotatedAt,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if old.RotatedAt != nil {
+		query = `
+			delete from tokens
+			where family = $1
+		`
+		_, err = tx.ExecContext(ctx, query, old.Family)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+		return types.ErrTokenReused
+	}
+
+	query = `
+		update tokens
+		set rotated_at = now()
+		where id = $1
+	`
+	_, err = tx.ExecContext(ctx, query, old.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range []*types.Token{access, refresh} {
+		t.UserID = old.UserID
+		t.Family = old.Family
+		err = insertToken(ctx, tx, t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (m TokenModel) GetOne(pt types.PlainToken, scope types.TokenScope) (*types.Token, error) {
+func (m TokenModel) GetOne(ctx context.Context, pt types.PlainToken, scope types.TokenScope) (*types.Token, error) {
 	query := `
-		select coalesce(user_id, 0), expires_at, scope, is_admin
+		select id, user_id, expires_at, scope, ip, user_agent, last_used_at
 		from tokens
 		where hash = $1
 		and scope = $2
@@ -64,28 +183,209 @@ func (m TokenModel) GetOne(pt types.PlainToken, scope types.TokenScope) (*types.
 
 	var t types.Token
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return &t, types.MapError(
 		m.DB.QueryRowContext(ctx, query, args...).Scan(
+			&t.ID,
 			&t.UserID,
 			&t.ExpiresAt,
 			&t.Scope,
-			&t.IsAdmin,
+			&t.IP,
+			&t.UserAgent,
+			&t.LastUsedAt,
 		),
 		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
 	)
 }
 
-func (m TokenModel) DeleteForUser(scope string, userID int64) error {
+func (m TokenModel) DeleteForUser(ctx context.Context, scope string, userID int64) error {
 	query := `
 		delete from tokens
 		where user_id = $1 and scope = $2
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 	_, err := m.DB.ExecContext(ctx, query, userID, scope)
 	return err
 }
+
+// Touch records that the token was just used to authenticate a request.
+func (m TokenModel) Touch(ctx context.Context, id int64) error {
+	query := `
+		update tokens
+		set last_used_at = now()
+		where id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// GetSessionsForUser lists the unexpired authentication tokens that belong to the user.
+func (m TokenModel) GetSessionsForUser(ctx context.Context, userID int64) ([]*types.Session, error) {
+	query := `
+		select id, created_at, last_used_at, expires_at, ip, user_agent
+		from tokens
+		where user_id = $1
+		and scope = $2
+		and expires_at > $3
+		order by created_at desc, id desc
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, types.ScopeAuthentication, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*types.Session, 0, 4)
+	for rows.Next() {
+		var s types.Session
+		err := rows.Scan(
+			&s.ID,
+			&s.CreatedAt,
+			&s.LastUsedAt,
+			&s.ExpiresAt,
+			&s.IP,
+			&s.UserAgent,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteSession revokes one of the user's authentication tokens along with any other tokens in its family so that
+// the session's refresh token can't be used to start it up again.
+func (m TokenModel) DeleteSession(ctx context.Context, userID int64, id int64) error {
+	query := `
+		delete from tokens
+		where user_id = $2
+		and (
+			(id = $1 and scope = $3)
+			or family = (select family from tokens where id = $1 and scope = $3)
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID, types.ScopeAuthentication)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForUser lists every token that belongs to the user, whatever its scope, including the ones that have expired
+// but haven't been purged yet.
+func (m TokenModel) GetAllForUser(ctx context.Context, userID int64) ([]*types.TokenInfo, error) {
+	query := `
+		select id, user_id, scope, coalesce(family, ''), created_at, last_used_at, expires_at, rotated_at, ip, user_agent
+		from tokens
+		where user_id = $1
+		order by id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]*types.TokenInfo, 0, 4)
+	for rows.Next() {
+		var t types.TokenInfo
+		err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.Scope,
+			&t.Family,
+			&t.CreatedAt,
+			&t.LastUsedAt,
+			&t.ExpiresAt,
+			&t.RotatedAt,
+			&t.IP,
+			&t.UserAgent,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Delete revokes a token of any scope along with the rest of its family.
+func (m TokenModel) Delete(ctx context.Context, id int64) error {
+	query := `
+		delete from tokens
+		where id = $1
+		or family = (select family from tokens where id = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteExpired purges the tokens that can no longer be used and reports how many there were.
+func (m TokenModel) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `
+		delete from tokens
+		where expires_at <= $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
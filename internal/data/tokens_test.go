@@ -0,0 +1,122 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func TestTokenRotate(t *testing.T) {
+	eachStore(t, func(t *testing.T, models data.Models) {
+		ctx := context.Background()
+		user := insertUser(t, models, "User", "user@example.com")
+
+		family := types.NewTokenFamily()
+		access := types.GenerateToken(user.ID, time.Hour, types.ScopeAuthentication)
+		refresh := types.GenerateToken(user.ID, time.Hour, types.ScopeRefresh)
+		access.Family = family
+		refresh.Family = family
+		err := models.Token.InsertFamily(ctx, access, refresh)
+		if err != nil {
+			t.Fatalf("InsertFamily() returned an error: %v", err)
+		}
+
+		newAccess := types.GenerateToken(0, time.Hour, types.ScopeAuthentication)
+		newRefresh := types.GenerateToken(0, time.Hour, types.ScopeRefresh)
+		err = models.Token.Rotate(ctx, refresh.Plaintext, newAccess, newRefresh)
+		if err != nil {
+			t.Fatalf("Rotate() returned an error: %v", err)
+		}
+		if newAccess.UserID != user.ID || newRefresh.Family != family {
+			t.Errorf("Rotate() issued tokens for user %d in family %q", newAccess.UserID, newRefresh.Family)
+		}
+
+		err = models.Token.Rotate(ctx, "not-a-token", newAccess, newRefresh)
+		if !errors.Is(err, types.ErrRecordNotFound) {
+			t.Errorf("Rotate() of an unknown token returned %v; want %v", err, types.ErrRecordNotFound)
+		}
+
+		reused := []*types.Token{
+			types.GenerateToken(0, time.Hour, types.ScopeAuthentication),
+			types.GenerateToken(0, time.Hour, types.ScopeRefresh),
+		}
+		err = models.Token.Rotate(ctx, refresh.Plaintext, reused[0], reused[1])
+		if !errors.Is(err, types.ErrTokenReused) {
+			t.Fatalf("Rotate() of a rotated token returned %v; want %v", err, types.ErrTokenReused)
+		}
+
+		// Reuse revokes the whole family, including the tokens issued by the legitimate rotation.
+		for _, tok := range []*types.Token{access, newAccess} {
+			_, err = models.Token.GetOne(ctx, tok.Plaintext, types.ScopeAuthentication)
+			if !errors.Is(err, types.ErrRecordNotFound) {
+				t.Errorf("GetOne() of a revoked token returned %v; want %v", err, types.ErrRecordNotFound)
+			}
+		}
+	})
+}
+
+func TestTokenDeletes(t *testing.T) {
+	eachStore(t, func(t *testing.T, models data.Models) {
+		ctx := context.Background()
+		user := insertUser(t, models, "User", "user@example.com")
+
+		family := types.NewTokenFamily()
+		access := types.GenerateToken(user.ID, time.Hour, types.ScopeAuthentication)
+		refresh := types.GenerateToken(user.ID, time.Hour, types.ScopeRefresh)
+		access.Family = family
+		refresh.Family = family
+		expired := types.GenerateToken(user.ID, -time.Hour, types.ScopePasswordReset)
+		activation := types.GenerateToken(user.ID, time.Hour, types.ScopeActivation)
+		err := models.Token.InsertFamily(ctx, access, refresh, expired, activation)
+		if err != nil {
+			t.Fatalf("InsertFamily() returned an error: %v", err)
+		}
+
+		scopes := func() []types.TokenScope {
+			tokens, err := models.Token.GetAllForUser(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("GetAllForUser() returned an error: %v", err)
+			}
+			got := make([]types.TokenScope, 0, len(tokens))
+			for _, tok := range tokens {
+				got = append(got, tok.Scope)
+			}
+			return got
+		}
+
+		want := []types.TokenScope{
+			types.ScopeAuthentication,
+			types.ScopeRefresh,
+			types.ScopePasswordReset,
+			types.ScopeActivation,
+		}
+		if got := scopes(); !slices.Equal(got, want) {
+			t.Errorf("GetAllForUser() = %v; want %v", got, want)
+		}
+
+		count, err := models.Token.DeleteExpired(ctx)
+		if err != nil || count != 1 {
+			t.Errorf("DeleteExpired() = %d, %v; want 1", count, err)
+		}
+
+		// Deleting the access token takes its refresh token along since they share a family.
+		err = models.Token.Delete(ctx, access.ID)
+		if err != nil {
+			t.Errorf("Delete() returned an error: %v", err)
+		}
+		want = []types.TokenScope{types.ScopeActivation}
+		if got := scopes(); !slices.Equal(got, want) {
+			t.Errorf("GetAllForUser() after deleting = %v; want %v", got, want)
+		}
+
+		err = models.Token.Delete(ctx, access.ID)
+		if !errors.Is(err, types.ErrRecordNotFound) {
+			t.Errorf("Delete() of a deleted token returned %v; want %v", err, types.ErrRecordNotFound)
+		}
+	})
+}
@@ -19,7 +19,7 @@ type UserModel struct {
 var UserSearchFields = NewSearchFields("name", "email")
 var UserSortFields = NewSortFields("id", "created_at", "updated_at", "name", "email")
 
-func (m UserModel) GetVersion(id int64) (int64, error) {
+func (m UserModel) GetVersion(ctx context.Context, id int64) (int64, error) {
 	query := `
 		select version
 		from users
@@ -27,7 +27,7 @@ func (m UserModel) GetVersion(id int64) (int64, error) {
 	`
 	var version int64
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return version, types.MapError(
@@ -36,19 +36,20 @@ func (m UserModel) GetVersion(id int64) (int64, error) {
 	)
 }
 
-func (m UserModel) Insert(user *types.User) error {
+func (m UserModel) Insert(ctx context.Context, user *types.User) error {
 	query := `
 		insert into users (name, email, password_hash, activated)
-		values ($1, $2, $3, false)
+		values ($1, $2, $3, $4)
 		returning id, created_at, updated_at, version
 	`
 	args := []any{
 		user.Name,
 		user.Email,
 		user.HashedPassword,
+		user.Activated,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return types.MapError(m.DB.QueryRowContext(
@@ -66,18 +67,18 @@ func (m UserModel) Insert(user *types.User) error {
 
 }
 
-func (m UserModel) GetOne(id int64) (*types.User, error) {
+func (m UserModel) GetOne(ctx context.Context, id int64) (*types.User, error) {
 	query := `
-		select id, created_at, updated_at, activated, name, email, version
+		select id, created_at, updated_at, activated, is_admin, name, email, version
 		from (
-			select id, created_at, updated_at, activated, name, email, version
+			select id, created_at, updated_at, activated, is_admin, name, email, version
 			from users
 			where id = $1
 		)
 	`
 	var u types.User
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return &u, types.MapError(
@@ -86,6 +87,7 @@ func (m UserModel) GetOne(id int64) (*types.User, error) {
 			&u.CreatedAt,
 			&u.UpdatedAt,
 			&u.Activated,
+			&u.IsAdmin,
 			&u.Name,
 			&u.Email,
 			&u.Version,
@@ -94,15 +96,15 @@ func (m UserModel) GetOne(id int64) (*types.User, error) {
 	)
 }
 
-func (m UserModel) GetForLogin(l *types.Login) (*types.User, error) {
+func (m UserModel) GetForLogin(ctx context.Context, l *types.Login) (*types.User, error) {
 	query := `
-		select id, created_at, updated_at, activated, name, email, version, password_hash
+		select id, created_at, updated_at, activated, is_admin, name, email, version, password_hash
 		from users
 		where email = $1
 	`
 	var u types.User
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, l.Email).Scan(
@@ -110,6 +112,7 @@ func (m UserModel) GetForLogin(l *types.Login) (*types.User, error) {
 		&u.CreatedAt,
 		&u.UpdatedAt,
 		&u.Activated,
+		&u.IsAdmin,
 		&u.Name,
 		&u.Email,
 		&u.Version,
@@ -123,7 +126,7 @@ func (m UserModel) GetForLogin(l *types.Login) (*types.User, error) {
 		return nil, types.ErrUserNotActivated
 	}
 
-	err = u.HashedPassword.Compare(l.Password)
+	err = u.HashedPassword.Compare(ctx, l.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -131,7 +134,33 @@ func (m UserModel) GetForLogin(l *types.Login) (*types.User, error) {
 	return &u, nil
 }
 
-func (m UserModel) GetForToken(t types.Token) (*types.User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email types.Email) (*types.User, error) {
+	query := `
+		select id, created_at, updated_at, activated, is_admin, name, email, version
+		from users
+		where email = $1
+	`
+	var u types.User
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	return &u, types.MapError(
+		m.DB.QueryRowContext(ctx, query, email).Scan(
+			&u.ID,
+			&u.CreatedAt,
+			&u.UpdatedAt,
+			&u.Activated,
+			&u.IsAdmin,
+			&u.Name,
+			&u.Email,
+			&u.Version,
+		),
+		types.ErrorMap{sql.ErrNoRows: types.ErrRecordNotFound},
+	)
+}
+
+func (m UserModel) GetForToken(ctx context.Context, t types.Token) (*types.User, error) {
 	slog.Debug("Getting user for token", "token", t.Hash, "scope", t.Scope)
 	query := `
 		select users.id, users.created_at, users.updated_at, users.name, users.email, users.version
@@ -143,7 +172,7 @@ func (m UserModel) GetForToken(t types.Token) (*types.User, error) {
 	`
 	var u types.User
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return &u, types.MapError(
@@ -159,7 +188,7 @@ func (m UserModel) GetForToken(t types.Token) (*types.User, error) {
 	)
 }
 
-func (m UserModel) GetMany(f Filters) ([]*types.User, *ListMetadata, error) {
+func (m UserModel) GetMany(ctx context.Context, f Filters) ([]*types.User, *ListMetadata, error) {
 	args := []any{}
 	query_parts := []string{`
 		select
@@ -170,6 +199,7 @@ func (m UserModel) GetMany(f Filters) ([]*types.User, *ListMetadata, error) {
 			name,
 			email,
 			activated,
+			is_admin,
 			version
 		from users
 	`}
@@ -208,7 +238,7 @@ func (m UserModel) GetMany(f Filters) ([]*types.User, *ListMetadata, error) {
 
 	slog.Debug("Assembled GetMany query", "query", query, "args", args)
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
@@ -229,6 +259,7 @@ func (m UserModel) GetMany(f Filters) ([]*types.User, *ListMetadata, error) {
 			&u.Name,
 			&u.Email,
 			&u.Activated,
+			&u.IsAdmin,
 			&u.Version,
 		)
 		if err != nil {
@@ -243,11 +274,11 @@ func (m UserModel) GetMany(f Filters) ([]*types.User, *ListMetadata, error) {
 	return users, &metadata, nil
 }
 
-func (m UserModel) Update(user *types.User) error {
+func (m UserModel) Update(ctx context.Context, user *types.User) error {
 	query := `
 		update users
-		set name = $1, email = $2, updated_at = $4, version = version + 1
-		where id = $5 and version = $6
+		set name = $1, email = $2, updated_at = $3, version = version + 1
+		where id = $4 and version = $5
 		returning version
 	`
 	args := []any{
@@ -258,8 +289,11 @@ func (m UserModel) Update(user *types.User) error {
 		user.Version,
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
 	return types.MapError(
-		m.DB.QueryRow(query, args...).Scan(&user.Version),
+		m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version),
 		types.ErrorMap{
 			sql.ErrNoRows:       types.ErrEditConflict,
 			".*duplicate key.*": types.ErrDuplicateKey,
@@ -267,7 +301,7 @@ func (m UserModel) Update(user *types.User) error {
 	)
 }
 
-func (m UserModel) Activate(token types.Token) (int64, error) {
+func (m UserModel) Activate(ctx context.Context, token types.Token) (int64, error) {
 	query := `
 		update users
 		set activated = true
@@ -279,7 +313,7 @@ func (m UserModel) Activate(token types.Token) (int64, error) {
 
 	var id int64
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return id, types.MapError(
@@ -292,7 +326,7 @@ func (m UserModel) Activate(token types.Token) (int64, error) {
 	)
 }
 
-func (m UserModel) DeleteTokensForUser(userID int64, scope string) error {
+func (m UserModel) DeleteTokensForUser(ctx context.Context, userID int64, scope string) error {
 	query := `
 		with deleted as (
 			delete from tokens
@@ -303,7 +337,7 @@ func (m UserModel) DeleteTokensForUser(userID int64, scope string) error {
 		select count(*) from deleted
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	var count int64
@@ -317,6 +351,7 @@ func (m UserModel) DeleteTokensForUser(userID int64, scope string) error {
 }
 
 func (m UserModel) PartialUpdate(
+	ctx context.Context,
 	id int64,
 	version int64,
 	partial *types.PartialUser,
@@ -342,6 +377,12 @@ func (m UserModel) PartialUpdate(
 		i += 1
 	}
 
+	if partial.HashedPassword != nil {
+		query += fmt.Sprintf(", password_hash = $%d", i)
+		args = append(args, *partial.HashedPassword)
+		i += 1
+	}
+
 	query += fmt.Sprintf(`
 		where id = $%d and version = $%d
 		returning created_at, updated_at, name, email, version
@@ -351,7 +392,7 @@ func (m UserModel) PartialUpdate(
 		ID: id,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	return u, types.MapError(
@@ -366,13 +407,13 @@ func (m UserModel) PartialUpdate(
 	)
 }
 
-func (m UserModel) Delete(id int64) error {
+func (m UserModel) Delete(ctx context.Context, id int64) error {
 	query := `
 		delete from users
 		where id = $1
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.CFG.QueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
 	defer cancel()
 
 	result, err := m.DB.ExecContext(ctx, query, id)
@@ -391,3 +432,31 @@ func (m UserModel) Delete(id int64) error {
 
 	return nil
 }
+
+// SetAdmin marks the user as an admin. It doesn't activate the user.
+func (m UserModel) SetAdmin(ctx context.Context, id int64) error {
+	query := `
+		update users
+		set is_admin = true, updated_at = $1, version = version + 1
+		where id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, m.CFG.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return types.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func TestUserUpdates(t *testing.T) {
+	eachStore(t, func(t *testing.T, models data.Models) {
+		ctx := context.Background()
+		alice := insertUser(t, models, "Alice", "alice@example.com")
+		bob := insertUser(t, models, "Bob", "bob@example.com")
+		if alice.Version != 1 {
+			t.Errorf("Insert() set version %d; want 1", alice.Version)
+		}
+
+		// Emails are citext, so addresses that only differ by case are the same address.
+		err := models.User.Insert(ctx, &types.User{Name: "Imposter", Email: "ALICE@example.com", HashedPassword: []byte("x")})
+		if !errors.Is(err, types.ErrDuplicateKey) {
+			t.Errorf("Insert() of a duplicate email returned %v; want %v", err, types.ErrDuplicateKey)
+		}
+
+		got, err := models.User.GetByEmail(ctx, "Alice@Example.com")
+		if err != nil || got.ID != alice.ID {
+			t.Errorf("GetByEmail() = %+v, %v; want user %d", got, err, alice.ID)
+		}
+
+		stale := *alice
+		alice.Name = "Alice Liddell"
+		err = models.User.Update(ctx, alice)
+		if err != nil {
+			t.Fatalf("Update() returned an error: %v", err)
+		}
+		if alice.Version != 2 {
+			t.Errorf("Update() set version %d; want 2", alice.Version)
+		}
+
+		err = models.User.Update(ctx, &stale)
+		if !errors.Is(err, types.ErrEditConflict) {
+			t.Errorf("Update() with a stale version returned %v; want %v", err, types.ErrEditConflict)
+		}
+
+		bob.Email = "Alice@example.com"
+		err = models.User.Update(ctx, bob)
+		if !errors.Is(err, types.ErrDuplicateKey) {
+			t.Errorf("Update() to a taken email returned %v; want %v", err, types.ErrDuplicateKey)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// Claims are the facts about a user that an access token carries so that requests can be authenticated without
+// looking anything up.
+type Claims struct {
+	TokenID   int64
+	UserID    int64
+	Email     types.Email
+	Family    string
+	Perms     []types.PermCode
+	Admin     bool
+	ExpiresAt time.Time
+}
+
+type wireClaims struct {
+	josejwt.Claims
+	Email  types.Email      `json:"email,omitempty"`
+	Family string           `json:"sid"`
+	Perms  []types.PermCode `json:"perms"`
+	Admin  bool             `json:"adm,omitempty"`
+}
+
+type key struct {
+	id     string
+	signer any
+	public any
+}
+
+// KeySet signs access tokens with its first key and verifies them with any of its keys. To rotate keys, put the new
+// key first and keep the old one around until the tokens it signed have expired.
+type KeySet struct {
+	issuer string
+	alg    jose.SignatureAlgorithm
+	keys   []key
+}
+
+// New builds a KeySet from the configured keys. EdDSA keys are base64 encoded Ed25519 seeds and HS256 keys are base64
+// encoded secrets. In development, a throwaway key is generated if none are configured.
+func New(cfg data.Config) (*KeySet, error) {
+	ks := &KeySet{issuer: cfg.JWTIssuer, alg: jose.SignatureAlgorithm(cfg.JWTAlgorithm)}
+	if ks.alg != jose.EdDSA && ks.alg != jose.HS256 {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", cfg.JWTAlgorithm)
+	}
+
+	encoded := cfg.JWTKeys
+	if len(encoded) == 0 {
+		if !cfg.APIEnv.IsDev() {
+			return nil, fmt.Errorf("at least one JWT key must be configured")
+		}
+		slog.Warn("No JWT keys configured. Generating a throwaway key")
+		raw := make([]byte, 32)
+		_, _ = rand.Read(raw)
+		encoded = []string{base64.StdEncoding.EncodeToString(raw)}
+	}
+
+	for i, e := range encoded {
+		raw, err := base64.StdEncoding.DecodeString(e)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode JWT key %d: %w", i, err)
+		}
+
+		var k key
+		switch ks.alg {
+		case jose.EdDSA:
+			if len(raw) != ed25519.SeedSize {
+				return nil, fmt.Errorf("JWT key %d must be a %d byte Ed25519 seed", i, ed25519.SeedSize)
+			}
+			priv := ed25519.NewKeyFromSeed(raw)
+			pub := priv.Public().(ed25519.PublicKey)
+			k = key{id: keyID(pub), signer: priv, public: pub}
+		case jose.HS256:
+			if len(raw) < 32 {
+				return nil, fmt.Errorf("JWT key %d must be at least 32 bytes", i)
+			}
+			k = key{id: keyID(raw), signer: raw, public: raw}
+		}
+		ks.keys = append(ks.keys, k)
+	}
+
+	return ks, nil
+}
+
+// keyID derives a stable identifier for a key so that tokens can name the key that signed them.
+func keyID(material []byte) string {
+	sum := sha256.Sum256(material)
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// Sign issues a token carrying the claims.
+func (ks *KeySet) Sign(c Claims) (string, error) {
+	k := ks.keys[0]
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: ks.alg, Key: k.signer},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", k.id),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	wc := wireClaims{
+		Claims: josejwt.Claims{
+			Issuer:   ks.issuer,
+			Subject:  strconv.FormatInt(c.UserID, 10),
+			ID:       strconv.FormatInt(c.TokenID, 10),
+			IssuedAt: josejwt.NewNumericDate(now),
+			Expiry:   josejwt.NewNumericDate(c.ExpiresAt),
+		},
+		Email:  c.Email,
+		Family: c.Family,
+		Perms:  c.Perms,
+		Admin:  c.Admin,
+	}
+	return josejwt.Signed(signer).Claims(wc).Serialize()
+}
+
+// Verify checks the token's signature, issuer, and expiry and returns its claims.
+func (ks *KeySet) Verify(raw string) (*Claims, error) {
+	t, err := josejwt.ParseSigned(raw, []jose.SignatureAlgorithm{ks.alg})
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Headers) != 1 {
+		return nil, fmt.Errorf("token must have exactly one signature")
+	}
+
+	var k *key
+	for i := range ks.keys {
+		if ks.keys[i].id == t.Headers[0].KeyID {
+			k = &ks.keys[i]
+			break
+		}
+	}
+	if k == nil {
+		return nil, fmt.Errorf("token was signed with an unknown key")
+	}
+
+	var wc wireClaims
+	err = t.Claims(k.public, &wc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = wc.ValidateWithLeeway(josejwt.Expected{Issuer: ks.issuer, Time: time.Now()}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if wc.Expiry == nil {
+		return nil, fmt.Errorf("token must expire")
+	}
+
+	userID, err := strconv.ParseInt(wc.Subject, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %w", err)
+	}
+	tokenID, err := strconv.ParseInt(wc.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token ID: %w", err)
+	}
+
+	return &Claims{
+		TokenID:   tokenID,
+		UserID:    userID,
+		Email:     wc.Email,
+		Family:    wc.Family,
+		Perms:     wc.Perms,
+		Admin:     wc.Admin,
+		ExpiresAt: wc.Expiry.Time(),
+	}, nil
+}
+
+// JWKS returns the public keys that clients can use to verify tokens. Symmetric keys are secret, so nothing is
+// published for HS256.
+func (ks *KeySet) JWKS() jose.JSONWebKeySet {
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	if ks.alg != jose.EdDSA {
+		return jwks
+	}
+	for _, k := range ks.keys {
+		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
+			Key:       k.public,
+			KeyID:     k.id,
+			Algorithm: string(ks.alg),
+			Use:       "sig",
+		})
+	}
+	return jwks
+}
@@ -0,0 +1,157 @@
+package jwt_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/jwt"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+func newKey(t *testing.T) string {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func newKeySet(t *testing.T, alg string, keys ...string) *jwt.KeySet {
+	ks, err := jwt.New(data.Config{
+		APIEnv:       types.EnvProd,
+		JWTAlgorithm: alg,
+		JWTIssuer:    "the-hunt",
+		JWTKeys:      keys,
+	})
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	return ks
+}
+
+func TestSignAndVerify(t *testing.T) {
+	claims := jwt.Claims{
+		TokenID:   7,
+		UserID:    42,
+		Email:     "hunter@example.com",
+		Family:    "FAMILY",
+		Perms:     []types.PermCode{types.CompanyRead, types.PostingRead},
+		Admin:     true,
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	oldKey := newKey(t)
+	newKey := newKey(t)
+
+	cases := []struct {
+		name    string
+		signer  *jwt.KeySet
+		checker *jwt.KeySet
+		expires time.Time
+		tamper  func(string) string
+		wantErr bool
+	}{
+		{
+			name:    "EdDSA",
+			signer:  newKeySet(t, "EdDSA", oldKey),
+			checker: newKeySet(t, "EdDSA", oldKey),
+		},
+		{
+			name:    "HS256",
+			signer:  newKeySet(t, "HS256", oldKey),
+			checker: newKeySet(t, "HS256", oldKey),
+		},
+		{
+			name:    "rotated key",
+			signer:  newKeySet(t, "EdDSA", oldKey),
+			checker: newKeySet(t, "EdDSA", newKey, oldKey),
+		},
+		{
+			name:    "retired key",
+			signer:  newKeySet(t, "EdDSA", oldKey),
+			checker: newKeySet(t, "EdDSA", newKey),
+			wantErr: true,
+		},
+		{
+			name:    "wrong algorithm",
+			signer:  newKeySet(t, "HS256", oldKey),
+			checker: newKeySet(t, "EdDSA", oldKey),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			signer:  newKeySet(t, "EdDSA", oldKey),
+			checker: newKeySet(t, "EdDSA", oldKey),
+			expires: time.Now().Add(-time.Minute),
+			wantErr: true,
+		},
+		{
+			name:    "tampered",
+			signer:  newKeySet(t, "EdDSA", oldKey),
+			checker: newKeySet(t, "EdDSA", oldKey),
+			tamper:  func(s string) string { return s[:len(s)-4] + "AAAA" },
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		in := claims
+		if !c.expires.IsZero() {
+			in.ExpiresAt = c.expires
+		}
+
+		signed, err := c.signer.Sign(in)
+		if err != nil {
+			t.Errorf("%s: Sign() returned an error: %v", c.name, err)
+			continue
+		}
+		if c.tamper != nil {
+			signed = c.tamper(signed)
+		}
+
+		out, err := c.checker.Verify(signed)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: Verify() did not return an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Verify() returned an error: %v", c.name, err)
+			continue
+		}
+		if out.TokenID != in.TokenID || out.UserID != in.UserID || out.Family != in.Family || !out.Admin {
+			t.Errorf("%s: Verify() = %+v; want %+v", c.name, out, in)
+		}
+		if len(out.Perms) != len(in.Perms) {
+			t.Errorf("%s: Verify() perms = %v; want %v", c.name, out.Perms, in.Perms)
+		}
+		if !out.ExpiresAt.Equal(in.ExpiresAt) {
+			t.Errorf("%s: Verify() expires_at = %v; want %v", c.name, out.ExpiresAt, in.ExpiresAt)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	cases := []struct {
+		name     string
+		alg      string
+		keyCount int
+		wantKeys int
+	}{
+		{name: "EdDSA publishes every key", alg: "EdDSA", keyCount: 2, wantKeys: 2},
+		{name: "HS256 publishes nothing", alg: "HS256", keyCount: 2, wantKeys: 0},
+	}
+	for _, c := range cases {
+		keys := make([]string, c.keyCount)
+		for i := range keys {
+			keys[i] = newKey(t)
+		}
+		got := len(newKeySet(t, c.alg, keys...).JWKS().Keys)
+		if got != c.wantKeys {
+			t.Errorf("%s: JWKS() has %d keys; want %d", c.name, got, c.wantKeys)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package logs
 
 import (
+	"context"
 	"log/slog"
 	"os"
 
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/dusktreader/the-hunt/internal/data"
 	"github.com/dusktreader/the-hunt/internal/types"
 )
@@ -15,5 +18,52 @@ func InitLogger(cfg data.Config) {
 	if cfg.APIEnv == types.EnvDev {
 		logOpts.Level = slog.LevelDebug
 	}
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, logOpts)))
+	slog.SetDefault(slog.New(NewContextHandler(slog.NewJSONHandler(os.Stdout, logOpts))))
+}
+
+type contextKey string
+
+const requestIDContextKey = contextKey("request-id")
+
+// WithRequestID stores the ID of the request that the context belongs to.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestID returns an empty string if the context doesn't belong to a request.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// ContextHandler adds the request ID and the IDs of the active span to every record that is logged with a context. That
+// way all of the lines for one request can be found together, and they can be matched up with its trace.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewContextHandler(h.Handler.WithAttrs(attrs))
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return NewContextHandler(h.Handler.WithGroup(name))
 }
@@ -0,0 +1,72 @@
+package logs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/dusktreader/the-hunt/internal/logs"
+)
+
+func TestContextHandler(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	spanCtx, span := tp.Tracer("test").Start(context.Background(), "request")
+	span.End()
+	stub := exporter.GetSpans()[0]
+
+	cases := []struct {
+		name          string
+		ctx           context.Context
+		wantRequestID string
+		wantTraceID   string
+		wantSpanID    string
+	}{
+		{
+			name:        "active span",
+			ctx:         spanCtx,
+			wantTraceID: stub.SpanContext.TraceID().String(),
+			wantSpanID:  stub.SpanContext.SpanID().String(),
+		},
+		{
+			name:          "request ID",
+			ctx:           logs.WithRequestID(context.Background(), "REQUEST"),
+			wantRequestID: "REQUEST",
+		},
+		{
+			name:          "request ID and active span",
+			ctx:           logs.WithRequestID(spanCtx, "REQUEST"),
+			wantRequestID: "REQUEST",
+			wantTraceID:   stub.SpanContext.TraceID().String(),
+			wantSpanID:    stub.SpanContext.SpanID().String(),
+		},
+		{name: "plain context", ctx: context.Background()},
+	}
+	for _, c := range cases {
+		buf := new(bytes.Buffer)
+		logger := slog.New(logs.NewContextHandler(slog.NewJSONHandler(buf, nil))).With("component", "test")
+		logger.InfoContext(c.ctx, "hello")
+
+		var record map[string]any
+		err := json.Unmarshal(buf.Bytes(), &record)
+		if err != nil {
+			t.Fatalf("%s: couldn't parse log record: %v", c.name, err)
+		}
+		requestID, _ := record["request_id"].(string)
+		traceID, _ := record["trace_id"].(string)
+		spanID, _ := record["span_id"].(string)
+		if requestID != c.wantRequestID {
+			t.Errorf("%s: got request_id=%q; want %q", c.name, requestID, c.wantRequestID)
+		}
+		if traceID != c.wantTraceID || spanID != c.wantSpanID {
+			t.Errorf("%s: got trace_id=%q span_id=%q; want %q and %q", c.name, traceID, spanID, c.wantTraceID, c.wantSpanID)
+		}
+	}
+}
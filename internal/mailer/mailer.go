@@ -2,23 +2,31 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/wneessen/go-mail"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	ht "html/template"
 	tt "text/template"
 
 	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/metrics"
 	"github.com/dusktreader/the-hunt/internal/types"
 )
 
 //go:embed "templates"
 var templateFS embed.FS
 
+var tracer = otel.Tracer("github.com/dusktreader/the-hunt/internal/mailer")
+
 type Mailer struct {
 	client     *mail.Client
 	sender     string
@@ -63,8 +71,19 @@ func New(cfg data.Config) (*Mailer, error) {
 	return mailer, nil
 }
 
-func (m *Mailer) Send(args ...any) error {
-	slog.Debug("Sending email", "args", args)
+// Send renders the template and delivers it to the recipient. The whole send is traced as one span with a child span
+// for every delivery attempt so that slow or flaky SMTP servers show up in the trace.
+func (m *Mailer) Send(ctx context.Context, args ...any) (err error) {
+	ctx, span := tracer.Start(ctx, "mailer.Send")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "couldn't send email")
+		}
+		span.End()
+	}()
+
+	slog.DebugContext(ctx, "Sending email", "args", args)
 	recipient, ok := args[0].(types.Email)
 	if !ok {
 		return fmt.Errorf("invalid recipient type at args index 0")
@@ -76,6 +95,7 @@ func (m *Mailer) Send(args ...any) error {
 	}
 
 	data := args[2]
+	span.SetAttributes(attribute.String("mail.template", templateFile))
 
 	textTmpl, err := tt.New("").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
@@ -122,7 +142,7 @@ func (m *Mailer) Send(args ...any) error {
 	msg.AddAlternativeString(mail.TypeTextHTML, htmlBody.String())
 
 	for i := range m.retryCount {
-		err = m.client.DialAndSend(msg)
+		err = m.attempt(ctx, msg, i+1)
 		if err == nil {
 			break
 		}
@@ -132,10 +152,24 @@ func (m *Mailer) Send(args ...any) error {
 	}
 
 	if err != nil {
-		slog.Error("Failed to send email", "error", err)
+		slog.ErrorContext(ctx, "Failed to send email", "error", err)
+		metrics.MailSends.WithLabelValues(templateFile, "failed").Inc()
 		return err
 	} else {
-		slog.Debug("Sent registration email", "email", recipient)
+		metrics.MailSends.WithLabelValues(templateFile, "sent").Inc()
+		slog.DebugContext(ctx, "Sent registration email", "email", recipient)
 	}
 	return nil
 }
+
+func (m *Mailer) attempt(ctx context.Context, msg *mail.Msg, n int) error {
+	ctx, span := tracer.Start(ctx, "mailer.attempt", trace.WithAttributes(attribute.Int("mail.attempt", n)))
+	defer span.End()
+
+	err := m.client.DialAndSendWithContext(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "delivery attempt failed")
+	}
+	return err
+}
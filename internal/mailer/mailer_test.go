@@ -0,0 +1,87 @@
+package mailer_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/mailer"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// closedPort finds a local port that nothing is listening on so that every delivery attempt fails right away.
+func closedPort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't find a free port: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+func TestSendSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	m, err := mailer.New(data.Config{
+		APIEnv:          types.EnvDev,
+		MailHost:        "127.0.0.1",
+		MailPort:        closedPort(t),
+		MailSndr:        "dev@localhost",
+		MailSendRetries: 3,
+		MailRetryDelay:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	err = m.Send(ctx, types.Email("hunter@example.com"), "account_locked.tmpl", map[string]any{
+		"user":  &types.User{Name: "Hunter"},
+		"until": "later",
+	})
+	parent.End()
+	if err == nil {
+		t.Fatalf("Send() did not return an error")
+	}
+
+	spans := exporter.GetSpans()
+	var send tracetest.SpanStub
+	var attempts []tracetest.SpanStub
+	for _, s := range spans {
+		switch s.Name {
+		case "mailer.Send":
+			send = s
+		case "mailer.attempt":
+			attempts = append(attempts, s)
+		}
+	}
+
+	if send.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("mailer.Send span is not a child of the request span")
+	}
+	if send.Status.Code != codes.Error {
+		t.Errorf("mailer.Send span status = %v; want %v", send.Status.Code, codes.Error)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("got %d mailer.attempt spans; want 3", len(attempts))
+	}
+	for _, a := range attempts {
+		if a.Parent.SpanID() != send.SpanContext.SpanID() {
+			t.Errorf("mailer.attempt span is not a child of the mailer.Send span")
+		}
+		if a.Status.Code != codes.Error {
+			t.Errorf("mailer.attempt span status = %v; want %v", a.Status.Code, codes.Error)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "the_hunt"
+
+// Unmatched labels requests that never reached a route, such as unknown paths or requests that were rejected by
+// middleware first. Raw paths are never used as labels because every ID would create a new series.
+const Unmatched = "unmatched"
+
+// OtherMethod labels requests with a method that HTTP doesn't define. Clients can send any method they like, so using
+// it as is would let them create as many series as they want.
+const OtherMethod = "other"
+
+// Method returns the label for a request method.
+func Method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	}
+	return OtherMethod
+}
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	Requests = promauto.With(Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route template, method, and status code.",
+		},
+		[]string{"route", "method", "status"},
+	)
+
+	RequestDuration = promauto.With(Registry).NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template, method, and status code.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "status"},
+	)
+
+	MailSends = promauto.With(Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mail_sends_total",
+			Help:      "Attempts to send mail by template and outcome.",
+		},
+		[]string{"template", "outcome"},
+	)
+
+	RateLimitRejections = promauto.With(Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_rejections_total",
+			Help:      "Requests rejected for exceeding a rate limit policy.",
+		},
+		[]string{"policy"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB reports the connection pool stats of the database.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,82 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// Provider runs the authorization code flow against an external OpenID Connect identity provider.
+type Provider struct {
+	oauth    oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// New discovers the provider's endpoints and signing keys from its issuer URL.
+func New(ctx context.Context, cfg data.Config) (*Provider, error) {
+	p, err := gooidc.NewProvider(ctx, cfg.OIDCIssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't discover OIDC provider: %w", err)
+	}
+
+	return &Provider{
+		oauth: oauth2.Config{
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Endpoint:     p.Endpoint(),
+			Scopes:       cfg.OIDCScopes,
+		},
+		verifier: p.Verifier(&gooidc.Config{ClientID: cfg.OIDCClientID}),
+	}, nil
+}
+
+// AuthCodeURL builds the URL that sends the user to the provider to authenticate.
+func (p *Provider) AuthCodeURL(l *types.OIDCLogin) string {
+	return p.oauth.AuthCodeURL(l.State, gooidc.Nonce(l.Nonce), oauth2.S256ChallengeOption(l.Verifier))
+}
+
+// Exchange trades the authorization code from the provider's callback for an ID token and returns the identity that it
+// asserts. The token's signature, audience, expiry, and nonce are all checked.
+func (p *Provider) Exchange(ctx context.Context, code string, l *types.OIDCLogin) (*types.Identity, error) {
+	t, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(l.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't exchange authorization code: %w", err)
+	}
+
+	raw, ok := t.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response did not include an ID token")
+	}
+
+	idt, err := p.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't verify ID token: %w", err)
+	}
+	if idt.Nonce != l.Nonce {
+		return nil, fmt.Errorf("ID token nonce does not match the login request")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	err = idt.Claims(&claims)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse ID token claims: %w", err)
+	}
+
+	return &types.Identity{
+		Issuer:        idt.Issuer,
+		Subject:       idt.Subject,
+		Email:         types.Email(claims.Email),
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
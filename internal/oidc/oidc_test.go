@@ -0,0 +1,211 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+	"github.com/dusktreader/the-hunt/internal/oidc"
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+const clientID = "the-hunt"
+
+// mockIssuer is a minimal OpenID Connect provider. It hands out a single authorization code and only accepts it with
+// the PKCE verifier that matches the challenge from the authorization request.
+type mockIssuer struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	code      string
+	challenge string
+	nonce     string
+	claims    map[string]any
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() returned an error: %v", err)
+	}
+
+	mi := &mockIssuer{key: key, code: "mock-code"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", mi.discovery)
+	mux.HandleFunc("/keys", mi.keys)
+	mux.HandleFunc("/token", mi.token)
+	mi.Server = httptest.NewServer(mux)
+	t.Cleanup(mi.Close)
+	return mi
+}
+
+func (mi *mockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                mi.URL,
+		"authorization_endpoint":                mi.URL + "/authorize",
+		"token_endpoint":                        mi.URL + "/token",
+		"jwks_uri":                              mi.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (mi *mockIssuer) keys(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &mi.key.PublicKey, KeyID: "mock", Algorithm: "RS256", Use: "sig"},
+	}})
+}
+
+func (mi *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if r.PostFormValue("code") != mi.code || base64.RawURLEncoding.EncodeToString(sum[:]) != mi.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: mi.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "mock"),
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   mi.URL,
+		"sub":   "mock-subject",
+		"aud":   clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": mi.nonce,
+	}
+	for k, v := range mi.claims {
+		claims[k] = v
+	}
+	raw, err := jwt.Signed(signer).Claims(claims).Serialize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     raw,
+	})
+}
+
+// authorize plays the part of the user's browser visiting the authorization URL.
+func (mi *mockIssuer) authorize(t *testing.T, authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("couldn't parse authorization URL: %v", err)
+	}
+	qs := u.Query()
+	if qs.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL code_challenge_method = %q; want S256", qs.Get("code_challenge_method"))
+	}
+	mi.challenge = qs.Get("code_challenge")
+	mi.nonce = qs.Get("nonce")
+}
+
+func TestExchange(t *testing.T) {
+	cases := []struct {
+		name         string
+		code         string
+		tamper       func(l *types.OIDCLogin)
+		claims       map[string]any
+		wantErr      bool
+		wantEmail    types.Email
+		wantVerified bool
+	}{
+		{
+			name:         "verified email",
+			code:         "mock-code",
+			claims:       map[string]any{"email": "hunter@example.com", "email_verified": true, "name": "Hunter"},
+			wantEmail:    "hunter@example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "unverified email",
+			code:         "mock-code",
+			claims:       map[string]any{"email": "hunter@example.com"},
+			wantEmail:    "hunter@example.com",
+			wantVerified: false,
+		},
+		{
+			name:    "wrong code",
+			code:    "not-the-code",
+			wantErr: true,
+		},
+		{
+			name:    "wrong verifier",
+			code:    "mock-code",
+			tamper:  func(l *types.OIDCLogin) { l.Verifier = types.NewOIDCLogin(time.Minute).Verifier },
+			wantErr: true,
+		},
+		{
+			name:    "wrong nonce",
+			code:    "mock-code",
+			tamper:  func(l *types.OIDCLogin) { l.Nonce = "not-the-nonce" },
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		mi := newMockIssuer(t)
+		mi.claims = c.claims
+
+		p, err := oidc.New(context.Background(), data.Config{
+			OIDCIssuerURL:   mi.URL,
+			OIDCClientID:    clientID,
+			OIDCRedirectURL: "http://localhost/v1/auth/oidc/callback",
+			OIDCScopes:      []string{"openid", "email"},
+		})
+		if err != nil {
+			t.Fatalf("%s: New() returned an error: %v", c.name, err)
+		}
+
+		l := types.NewOIDCLogin(time.Minute)
+		mi.authorize(t, p.AuthCodeURL(l))
+		if c.tamper != nil {
+			c.tamper(l)
+		}
+
+		id, err := p.Exchange(context.Background(), c.code, l)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: Exchange() did not return an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Exchange() returned an error: %v", c.name, err)
+			continue
+		}
+		if id.Issuer != mi.URL || id.Subject != "mock-subject" {
+			t.Errorf("%s: Exchange() identity = %s/%s; want %s/mock-subject", c.name, id.Issuer, id.Subject, mi.URL)
+		}
+		if id.Email != c.wantEmail {
+			t.Errorf("%s: Exchange() email = %q; want %q", c.name, id.Email, c.wantEmail)
+		}
+		if id.EmailVerified != c.wantVerified {
+			t.Errorf("%s: Exchange() email_verified = %v; want %v", c.name, id.EmailVerified, c.wantVerified)
+		}
+	}
+}
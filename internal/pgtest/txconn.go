@@ -0,0 +1,154 @@
+package pgtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// txConnector opens connections that stay inside a single transaction for their whole life. Closing the connection
+// rolls the transaction back, so nothing that happened on it is ever committed.
+type txConnector struct {
+	driver.Connector
+}
+
+func (c txConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &txConn{Conn: conn}
+	err = tc.exec(ctx, "begin")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// txConn turns the transactions that the code under test begins into savepoints of the outer transaction.
+//
+// Outside of those transactions, every statement gets a savepoint of its own. A failed statement would otherwise
+// abort the outer transaction and every statement after it would fail too, which isn't what happens when statements
+// are committed one at a time.
+type txConn struct {
+	driver.Conn
+	depth      int
+	savepoints int
+}
+
+func (c *txConn) exec(ctx context.Context, query string) error {
+	_, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, query, nil)
+	return err
+}
+
+// savepoint starts a new savepoint and returns its name.
+func (c *txConn) savepoint(ctx context.Context) (string, error) {
+	c.savepoints += 1
+	name := fmt.Sprintf("pgtest_%d", c.savepoints)
+	return name, c.exec(ctx, "savepoint "+name)
+}
+
+// finish releases the savepoint if the statement succeeded and rolls back to it if it didn't.
+func (c *txConn) finish(name string, err error) error {
+	if err != nil {
+		return errors.Join(err, c.exec(context.Background(), "rollback to savepoint "+name))
+	}
+	return c.exec(context.Background(), "release savepoint "+name)
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer := c.Conn.(driver.ExecerContext)
+	if c.depth > 0 {
+		return execer.ExecContext(ctx, query, args)
+	}
+
+	name, err := c.savepoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := execer.ExecContext(ctx, query, args)
+	return result, c.finish(name, err)
+}
+
+func (c *txConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer := c.Conn.(driver.QueryerContext)
+	if c.depth > 0 {
+		return queryer.QueryContext(ctx, query, args)
+	}
+
+	name, err := c.savepoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, c.finish(name, err)
+	}
+	return &txRows{Rows: rows, conn: c, savepoint: name}, nil
+}
+
+func (c *txConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *txConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	name, err := c.savepoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.depth += 1
+	return &txSavepoint{conn: c, name: name}, nil
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *txConn) Close() error {
+	err := c.exec(context.Background(), "rollback")
+	return errors.Join(err, c.Conn.Close())
+}
+
+// txRows finishes the savepoint of a query once its rows are closed since errors can turn up while the rows are read.
+type txRows struct {
+	driver.Rows
+	conn      *txConn
+	savepoint string
+	err       error
+}
+
+func (r *txRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+	return err
+}
+
+func (r *txRows) Close() error {
+	err := r.Rows.Close()
+	if r.err != nil {
+		// Next already reported the error, so only a failure to roll back is news.
+		return errors.Join(err, r.conn.exec(context.Background(), "rollback to savepoint "+r.savepoint))
+	}
+	return r.conn.finish(r.savepoint, err)
+}
+
+type txSavepoint struct {
+	conn *txConn
+	name string
+}
+
+func (s *txSavepoint) Commit() error {
+	s.conn.depth -= 1
+	return s.conn.exec(context.Background(), "release savepoint "+s.name)
+}
+
+func (s *txSavepoint) Rollback() error {
+	s.conn.depth -= 1
+	return s.conn.exec(context.Background(), "rollback to savepoint "+s.name)
+}
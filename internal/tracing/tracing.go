@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+
+	"github.com/dusktreader/the-hunt/internal/data"
+)
+
+const ServiceName = "the-hunt"
+
+// Init installs a global tracer provider that exports spans over OTLP/HTTP. Packages start their spans with tracers
+// from the global provider, so tests can install one with an in-memory exporter instead. The returned function flushes
+// any spans that are still buffered and should be called before the app exits. When tracing is disabled, nothing is
+// exported but incoming trace context is still propagated so that log records can be matched to the caller's trace.
+func Init(ctx context.Context, cfg data.Config, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.TracingEndpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.TracingEndpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(
+			semconv.ServiceName(ServiceName),
+			semconv.ServiceVersion(version),
+			semconv.DeploymentEnvironmentName(string(cfg.APIEnv)),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+// ApiKey is a long-lived credential that a user creates for scripts. It only carries the permissions that were
+// granted to it when it was created.
+type ApiKey struct {
+	ID          int64      `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UserID      int64      `json:"user_id"`
+	Name        string     `json:"name"`
+	Plaintext   PlainToken `json:"key,omitzero"`
+	Hash        []byte     `json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	Permissions []PermCode `json:"permissions"`
+}
+
+func GenerateApiKey(userID int64, name string, expiresAt *time.Time, perms ...PermCode) *ApiKey {
+	plaintext := rand.Text()
+	return &ApiKey{
+		UserID:      userID,
+		Name:        name,
+		Plaintext:   PlainToken(plaintext),
+		Hash:        Hash(plaintext),
+		ExpiresAt:   expiresAt,
+		Permissions: perms,
+	}
+}
+
+// PermissionSet returns the key's permissions in the form that is bound to the request context.
+func (k *ApiKey) PermissionSet() *PermissionSet {
+	return NewPermissionSet(k.Permissions...)
+}
+
+// Validate checks the key's fields. The granted permissions must be drawn from the allowed set, which should be the
+// permissions held by the credential used to create the key.
+func (k *ApiKey) Validate(v *validator.Validator, allowed *PermissionSet) {
+	v.Check(k.Name != "", "name", "must be provided")
+	v.Check(len(k.Name) <= 128, "name", "must not be more than 128 bytes")
+
+	if k.ExpiresAt != nil {
+		v.Check(k.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+
+	v.Check(len(k.Permissions) > 0, "permissions", "must not be empty")
+	v.Check(validator.Unique(k.Permissions), "permissions", "must not contain duplicate items")
+	v.Check(allowed.Subset(k.PermissionSet()), "permissions", "must only include permissions that you hold")
+}
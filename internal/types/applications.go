@@ -0,0 +1,114 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+type ApplicationStatus string
+
+const (
+	StatusInterested   ApplicationStatus = "interested"
+	StatusApplied      ApplicationStatus = "applied"
+	StatusScreening    ApplicationStatus = "screening"
+	StatusInterviewing ApplicationStatus = "interviewing"
+	StatusOffer        ApplicationStatus = "offer"
+	StatusAccepted     ApplicationStatus = "accepted"
+	StatusRejected     ApplicationStatus = "rejected"
+	StatusWithdrawn    ApplicationStatus = "withdrawn"
+)
+
+// The allowed moves through the pipeline. Any status missing from the map is terminal.
+var applicationTransitions = map[ApplicationStatus][]ApplicationStatus{
+	StatusInterested:   {StatusApplied, StatusWithdrawn},
+	StatusApplied:      {StatusScreening, StatusInterviewing, StatusRejected, StatusWithdrawn},
+	StatusScreening:    {StatusInterviewing, StatusRejected, StatusWithdrawn},
+	StatusInterviewing: {StatusOffer, StatusRejected, StatusWithdrawn},
+	StatusOffer:        {StatusAccepted, StatusRejected, StatusWithdrawn},
+}
+
+var ApplicationStatuses = []ApplicationStatus{
+	StatusInterested,
+	StatusApplied,
+	StatusScreening,
+	StatusInterviewing,
+	StatusOffer,
+	StatusAccepted,
+	StatusRejected,
+	StatusWithdrawn,
+}
+
+func (s ApplicationStatus) Validate(v *validator.Validator) {
+	v.Check(
+		validator.PermittedValue(s, ApplicationStatuses...),
+		"status",
+		"must be a known application status",
+	)
+}
+
+func (s ApplicationStatus) IsTerminal() bool {
+	_, ok := applicationTransitions[s]
+	return !ok
+}
+
+func (s ApplicationStatus) CanTransitionTo(next ApplicationStatus) bool {
+	return slices.Contains(applicationTransitions[s], next)
+}
+
+type Application struct {
+	ID        int64             `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	OwnerID   int64             `json:"owner_id"`
+	PostingID int64             `json:"posting_id"`
+	Status    ApplicationStatus `json:"status"`
+	Notes     string            `json:"notes,omitzero"`
+	Version   int64             `json:"version"`
+}
+
+type ApplicationTransition struct {
+	ID            int64              `json:"id"`
+	CreatedAt     time.Time          `json:"created_at"`
+	ApplicationID int64              `json:"application_id"`
+	FromStatus    *ApplicationStatus `json:"from_status"`
+	ToStatus      ApplicationStatus  `json:"to_status"`
+	Note          string             `json:"note,omitzero"`
+}
+
+func (a *Application) Validate(v *validator.Validator) {
+	v.Check(a.PostingID > 0, "posting_id", "must be provided")
+
+	a.Status.Validate(v)
+	v.Check(
+		validator.PermittedValue(a.Status, StatusInterested, StatusApplied),
+		"status",
+		"new applications must start as interested or applied",
+	)
+
+	v.Check(len(a.Notes) <= 4096, "notes", "must not be more than 4096 bytes")
+}
+
+func (at *ApplicationTransition) Validate(v *validator.Validator) {
+	at.ToStatus.Validate(v)
+	if !v.Valid() {
+		return
+	}
+
+	if at.FromStatus != nil {
+		from := *at.FromStatus
+		if from.IsTerminal() {
+			v.AddError("status", fmt.Sprintf("cannot transition out of terminal status %s", from))
+		} else {
+			v.Check(
+				from.CanTransitionTo(at.ToStatus),
+				"status",
+				fmt.Sprintf("cannot transition from %s to %s", from, at.ToStatus),
+			)
+		}
+	}
+
+	v.Check(len(at.Note) <= 4096, "note", "must not be more than 4096 bytes")
+}
@@ -0,0 +1,78 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func TestApplicationTransitionValidate(t *testing.T) {
+	status := func(s types.ApplicationStatus) *types.ApplicationStatus {
+		return &s
+	}
+
+	cases := []struct {
+		name      string
+		from      *types.ApplicationStatus
+		to        types.ApplicationStatus
+		wantValid bool
+	}{
+		{
+			name:      "initial status",
+			from:      nil,
+			to:        types.StatusInterested,
+			wantValid: true,
+		},
+		{
+			name:      "interested to applied",
+			from:      status(types.StatusInterested),
+			to:        types.StatusApplied,
+			wantValid: true,
+		},
+		{
+			name:      "offer to accepted",
+			from:      status(types.StatusOffer),
+			to:        types.StatusAccepted,
+			wantValid: true,
+		},
+		{
+			name:      "screening to withdrawn",
+			from:      status(types.StatusScreening),
+			to:        types.StatusWithdrawn,
+			wantValid: true,
+		},
+		{
+			name:      "interested to offer skips the pipeline",
+			from:      status(types.StatusInterested),
+			to:        types.StatusOffer,
+			wantValid: false,
+		},
+		{
+			name:      "interviewing back to applied",
+			from:      status(types.StatusInterviewing),
+			to:        types.StatusApplied,
+			wantValid: false,
+		},
+		{
+			name:      "rejected is terminal",
+			from:      status(types.StatusRejected),
+			to:        types.StatusApplied,
+			wantValid: false,
+		},
+		{
+			name:      "unknown status",
+			from:      status(types.StatusApplied),
+			to:        types.ApplicationStatus("ghosted"),
+			wantValid: false,
+		},
+	}
+	for _, c := range cases {
+		v := validator.New()
+		at := &types.ApplicationTransition{FromStatus: c.from, ToStatus: c.to}
+		at.Validate(v)
+		if v.Valid() != c.wantValid {
+			t.Errorf("%s: expected valid=%v, got valid=%v (%v)", c.name, c.wantValid, v.Valid(), v.Errors())
+		}
+	}
+}
@@ -6,20 +6,36 @@ import (
 	"github.com/dusktreader/the-hunt/internal/validator"
 )
 
+type Visibility string
+
+const VisibilityPrivate Visibility = "private"
+const VisibilityPublic Visibility = "public"
+
+func (vis Visibility) Validate(v *validator.Validator) {
+	v.Check(
+		validator.PermittedValue(vis, VisibilityPrivate, VisibilityPublic),
+		"visibility",
+		"must be one of private or public",
+	)
+}
+
 type Company struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Name      string    `json:"name"`
-	URL       string    `json:"url,omitzero"`
-	TechStack []string  `json:"tech_stack,omitempty"`
-	Version   int64     `json:"version"`
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	OwnerID    int64      `json:"owner_id"`
+	Visibility Visibility `json:"visibility"`
+	Name       string     `json:"name"`
+	URL        string     `json:"url,omitzero"`
+	TechStack  []string   `json:"tech_stack,omitempty"`
+	Version    int64      `json:"version"`
 }
 
 type PartialCompany struct {
-	Name      *string  `json:"name"`
-	URL       *string  `json:"url"`
-	TechStack []string `json:"tech_stack"`
+	Name       *string     `json:"name"`
+	URL        *string     `json:"url"`
+	TechStack  []string    `json:"tech_stack"`
+	Visibility *Visibility `json:"visibility"`
 }
 
 func (c *Company) Validate(v *validator.Validator) {
@@ -32,6 +48,8 @@ func (c *Company) Validate(v *validator.Validator) {
 	v.Check(len(c.TechStack) > 0, "tech_stack", "must not be empty")
 	v.Check(len(c.TechStack) <= 5, "tech_stack", "must not be more than 5 items")
 	v.Check(validator.Unique(c.TechStack), "tech_stack", "must not contain duplicate items")
+
+	c.Visibility.Validate(v)
 }
 
 func (pc *PartialCompany) Validate(v *validator.Validator) {
@@ -49,4 +67,8 @@ func (pc *PartialCompany) Validate(v *validator.Validator) {
 		v.Check(len(pc.TechStack) <= 5, "tech_stack", "must not be more than 5 items")
 		v.Check(validator.Unique(pc.TechStack), "tech_stack", "must not contain duplicate items")
 	}
+
+	if pc.Visibility != nil {
+		pc.Visibility.Validate(v)
+	}
 }
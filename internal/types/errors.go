@@ -1,6 +1,7 @@
 package types
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -10,6 +11,9 @@ import (
 var (
 	ErrRecordNotFound   = errors.New("record not found")
 	ErrNoTokenMatch     = errors.New("no valid token")
+	ErrTokenReused      = errors.New("token reused")
+	ErrInvalidMFACode   = errors.New("invalid mfa code")
+	ErrAccountLocked    = errors.New("account locked")
 	ErrEditConflict     = errors.New("edit conflict")
 	ErrInvalidParam     = errors.New("invalid query parameter")
 	ErrDuplicateKey     = errors.New("duplicate key")
@@ -18,8 +22,26 @@ var (
 	ErrForbidden        = errors.New("forbidden")
 	ErrPasswordMismatch = errors.New("password mismatch")
 	ErrUserNotActivated = errors.New("user not activated")
+	ErrEmailNotVerified = errors.New("email not verified")
+	ErrTimeout          = errors.New("timed out")
 )
 
+// Postgres reports a statement that was canceled because its context ran out as its own error instead of the
+// context's error.
+var queryCanceled = regexp.MustCompile("canceling statement due to (user request|statement timeout)")
+
+// MapTimeout wraps errors caused by running out of time in ErrTimeout so that they can be told apart from other
+// failures. The original error is kept for logging.
+func MapTimeout(err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || queryCanceled.MatchString(err.Error()) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
 type ErrorMapUnion any
 
 type ErrorMap map[ErrorMapUnion]error
@@ -30,6 +52,9 @@ func MapError(err error, errMap ErrorMap) error {
 	}
 
 	slog.Debug("Attempting to map original error", "err", err)
+	if mapped := MapTimeout(err); mapped != err {
+		return mapped
+	}
 	for inErr, outErr := range errMap {
 		switch v := inErr.(type) {
 		case error:
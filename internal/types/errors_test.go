@@ -1,7 +1,9 @@
 package types_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/dusktreader/the-hunt/internal/types"
@@ -45,6 +47,11 @@ func TestMapError(t *testing.T) {
 			err:     nil,
 			wantErr: nil,
 		},
+		{
+			name:    "deadline mapped to timeout",
+			err:     fmt.Errorf("query failed: %w", context.DeadlineExceeded),
+			wantErr: types.ErrTimeout,
+		},
 	}
 	for _, c := range cases {
 		gotErr := types.MapError(c.err, errMap)
@@ -63,3 +70,28 @@ func TestMapError(t *testing.T) {
 	}
 	types.MapError(ErrTestHutt, badMap)
 }
+
+func TestMapTimeout(t *testing.T) {
+	cases := []struct {
+		name        string
+		err         error
+		wantTimeout bool
+	}{
+		{name: "nil argument", err: nil},
+		{name: "unrelated error", err: ErrTestJawa},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantTimeout: true},
+		{name: "wrapped deadline", err: fmt.Errorf("ping: %w", context.DeadlineExceeded), wantTimeout: true},
+		{name: "canceled statement", err: errors.New("pq: canceling statement due to user request"), wantTimeout: true},
+		{name: "statement timeout", err: errors.New("pq: canceling statement due to statement timeout"), wantTimeout: true},
+		{name: "already mapped", err: types.ErrTimeout, wantTimeout: true},
+	}
+	for _, c := range cases {
+		gotErr := types.MapTimeout(c.err)
+		if errors.Is(gotErr, types.ErrTimeout) != c.wantTimeout {
+			t.Errorf("%s: MapTimeout(%v) = %v; want timeout %v", c.name, c.err, gotErr, c.wantTimeout)
+		}
+		if c.err != nil && !errors.Is(gotErr, c.err) {
+			t.Errorf("%s: MapTimeout(%v) = %v; lost the original error", c.name, c.err, gotErr)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"time"
+)
+
+type LoginFailureKind string
+
+const FailureByEmail LoginFailureKind = "email"
+const FailureByIP LoginFailureKind = "ip"
+
+// LockoutPolicy decides how long logins are blocked after repeated failures. Once MaxFailures is reached, each
+// further failure doubles the lockout, starting at BaseDelay and never exceeding MaxDelay. Failures are forgotten
+// once Window has passed since the last one and since any lockout ended.
+type LockoutPolicy struct {
+	MaxFailures int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Window      time.Duration
+}
+
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if failures < p.MaxFailures {
+		return 0
+	}
+
+	exponent := failures - p.MaxFailures
+	if exponent > 30 {
+		return p.MaxDelay
+	}
+
+	delay := p.BaseDelay << exponent
+	if delay <= 0 || delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
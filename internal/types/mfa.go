@@ -0,0 +1,136 @@
+package types
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+// These follow the defaults from RFC 6238 since they are the only settings that every authenticator app supports.
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+	TOTPSkew   = 1
+)
+
+const RecoveryCodeCount = 10
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFA holds a user's second factor. The secret is stored as-is because it is needed to compute codes, but recovery
+// codes are only ever stored hashed.
+type MFA struct {
+	UserID   int64
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+type MFACode string
+
+func (c MFACode) Validate(v *validator.Validator) {
+	v.Check(c != "", "code", "must be provided")
+	v.Check(len(c) <= 32, "code", "must not be more than 32 bytes")
+}
+
+// IsTOTP reports whether the code looks like a TOTP code rather than a recovery code.
+func (c MFACode) IsTOTP() bool {
+	if len(c) != TOTPDigits {
+		return false
+	}
+	for _, r := range c {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize makes recovery codes match regardless of case or stray whitespace.
+func (c MFACode) Normalize() MFACode {
+	return MFACode(strings.ToLower(strings.TrimSpace(string(c))))
+}
+
+func NewTOTPSecret() string {
+	secret := make([]byte, 20)
+	rand.Read(secret)
+	return totpEncoding.EncodeToString(secret)
+}
+
+// TOTPStep returns the counter value that RFC 6238 derives from a point in time.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode computes the RFC 4226 HOTP value for the given step.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range TOTPDigits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// VerifyTOTP checks the code against the steps around the given time to allow for some clock drift. If the code
+// matches, the matching step is returned so that the caller can refuse to accept it a second time.
+func VerifyTOTP(secret string, code MFACode, t time.Time) (int64, bool) {
+	if !code.IsTOTP() {
+		return 0, false
+	}
+
+	now := TOTPStep(t)
+	for step := now - TOTPSkew; step <= now+TOTPSkew; step++ {
+		want, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(want), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI builds the otpauth URI that authenticator apps read from a QR code.
+func TOTPURI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+// GenerateRecoveryCodes creates single-use codes that can stand in for a TOTP code if the user loses their device.
+func GenerateRecoveryCodes() []MFACode {
+	codes := make([]MFACode, 0, RecoveryCodeCount)
+	for range RecoveryCodeCount {
+		text := strings.ToLower(rand.Text())
+		codes = append(codes, MFACode(text[:5]+"-"+text[5:10]))
+	}
+	return codes
+}
@@ -0,0 +1,66 @@
+package types_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+)
+
+// The RFC 6238 test vectors use 8 digits, so the expected codes here are the last 6 digits of the published values.
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	cases := []struct {
+		name     string
+		unix     int64
+		wantCode string
+	}{
+		{name: "59", unix: 59, wantCode: "287082"},
+		{name: "1111111109", unix: 1111111109, wantCode: "081804"},
+		{name: "1111111111", unix: 1111111111, wantCode: "050471"},
+		{name: "1234567890", unix: 1234567890, wantCode: "005924"},
+		{name: "2000000000", unix: 2000000000, wantCode: "279037"},
+		{name: "20000000000", unix: 20000000000, wantCode: "353130"},
+	}
+	for _, c := range cases {
+		gotCode, err := types.TOTPCode(secret, types.TOTPStep(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Errorf("%s: TOTPCode() returned an error: %v", c.name, err)
+		} else if gotCode != c.wantCode {
+			t.Errorf("%s: TOTPCode() = %q; want %q", c.name, gotCode, c.wantCode)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := types.NewTOTPSecret()
+	now := time.Unix(1_700_000_000, 0)
+	code := func(at time.Time) types.MFACode {
+		c, err := types.TOTPCode(secret, types.TOTPStep(at))
+		if err != nil {
+			t.Fatalf("TOTPCode() returned an error: %v", err)
+		}
+		return types.MFACode(c)
+	}
+
+	cases := []struct {
+		name   string
+		code   types.MFACode
+		wantOK bool
+	}{
+		{name: "current step", code: code(now), wantOK: true},
+		{name: "previous step", code: code(now.Add(-types.TOTPPeriod)), wantOK: true},
+		{name: "next step", code: code(now.Add(types.TOTPPeriod)), wantOK: true},
+		{name: "too old", code: code(now.Add(-3 * types.TOTPPeriod)), wantOK: false},
+		{name: "not digits", code: "abcdef", wantOK: false},
+		{name: "wrong length", code: "12345", wantOK: false},
+	}
+	for _, c := range cases {
+		_, gotOK := types.VerifyTOTP(secret, c.code, now)
+		if gotOK != c.wantOK {
+			t.Errorf("%s: VerifyTOTP() = %v; want %v", c.name, gotOK, c.wantOK)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"crypto/rand"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// OIDCLogin holds the secrets of an authorization request that is waiting for the identity provider to redirect back.
+// The state identifies the request, the nonce binds the ID token to it, and the verifier completes the PKCE exchange.
+type OIDCLogin struct {
+	State     string
+	Verifier  string
+	Nonce     string
+	ExpiresAt time.Time
+}
+
+func NewOIDCLogin(ttl time.Duration) *OIDCLogin {
+	return &OIDCLogin{
+		State:     rand.Text(),
+		Verifier:  oauth2.GenerateVerifier(),
+		Nonce:     rand.Text(),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+}
+
+// Identity is a user's identity as asserted by an external identity provider.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         Email
+	EmailVerified bool
+	Name          string
+}
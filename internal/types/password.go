@@ -1,14 +1,19 @@
 package types
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/dusktreader/the-hunt/internal/validator"
 )
 
+// Hashing is slow on purpose, so it gets its own spans to tell it apart from the rest of a request.
+var tracer = otel.Tracer("github.com/dusktreader/the-hunt/internal/types")
+
 type PlainPW string
 type HashPW []byte
 
@@ -18,7 +23,10 @@ func (pp PlainPW) Validate(v *validator.Validator) {
 	v.Check(len(pp) <= 128, "password", "must not be more than 128 bytes")
 }
 
-func NewHashPW(pp PlainPW) (HashPW, error) {
+func NewHashPW(ctx context.Context, pp PlainPW) (HashPW, error) {
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(pp), 12)
 	if err != nil {
 		return nil, err
@@ -27,14 +35,17 @@ func NewHashPW(pp PlainPW) (HashPW, error) {
 	return hash, nil
 }
 
-func (hp HashPW) Compare(pp PlainPW) error {
+func (hp HashPW) Compare(ctx context.Context, pp PlainPW) error {
+	ctx, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
 	err := bcrypt.CompareHashAndPassword(hp, []byte(pp))
 	if err != nil {
 		switch {
 		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
 			return ErrPasswordMismatch
 		default:
-			slog.Debug("Error comparing plaintext password to hash", "err", err)
+			slog.DebugContext(ctx, "Error comparing plaintext password to hash", "err", err)
 			return err
 		}
 	}
@@ -2,15 +2,22 @@ package types
 
 import (
 	"github.com/hashicorp/go-set/v3"
+
+	"github.com/dusktreader/the-hunt/internal/validator"
 )
 
 type PermCode string
 
 const (
-	CompanyRead  PermCode = "companies:read"
-	CompanyWrite PermCode = "companies:write"
-	UserRead     PermCode = "users:read"
-	UserWrite    PermCode = "users:write"
+	CompanyRead      PermCode = "companies:read"
+	CompanyWrite     PermCode = "companies:write"
+	UserRead         PermCode = "users:read"
+	UserWrite        PermCode = "users:write"
+	PostingRead      PermCode = "postings:read"
+	PostingWrite     PermCode = "postings:write"
+	ApplicationRead  PermCode = "applications:read"
+	ApplicationWrite PermCode = "applications:write"
+	PermissionAdmin  PermCode = "permissions:admin"
 )
 
 type PermissionSet = set.Set[PermCode]
@@ -28,6 +35,14 @@ func HasPerms(ps *PermissionSet, strategy PermissionStrategy, perms ...PermCode)
 	}
 }
 
+// ValidatePermCodes checks that a list of codes submitted by a client is free of duplicates and only contains codes
+// that exist.
+func ValidatePermCodes(v *validator.Validator, perms []PermCode, known *PermissionSet) {
+	v.Check(perms != nil, "permissions", "must be provided")
+	v.Check(validator.Unique(perms), "permissions", "must not contain duplicate items")
+	v.Check(known.Subset(set.From(perms)), "permissions", "must only include known permissions")
+}
+
 type PermissionStrategy string
 
 const All PermissionStrategy = "all"
@@ -0,0 +1,134 @@
+package types
+
+import (
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+type RemotePolicy string
+
+const RemoteOnsite RemotePolicy = "onsite"
+const RemoteHybrid RemotePolicy = "hybrid"
+const RemoteFull RemotePolicy = "remote"
+
+func (rp RemotePolicy) Validate(v *validator.Validator) {
+	v.Check(
+		validator.PermittedValue(rp, RemoteOnsite, RemoteHybrid, RemoteFull),
+		"remote",
+		"must be one of onsite, hybrid, or remote",
+	)
+}
+
+type Posting struct {
+	ID        int64        `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	OwnerID   int64        `json:"owner_id"`
+	CompanyID int64        `json:"company_id"`
+	Title     string       `json:"title"`
+	URL       string       `json:"url,omitzero"`
+	Location  string       `json:"location,omitzero"`
+	Remote    RemotePolicy `json:"remote"`
+	SalaryMin *int64       `json:"salary_min,omitempty"`
+	SalaryMax *int64       `json:"salary_max,omitempty"`
+	PostedAt  *time.Time   `json:"posted_at,omitempty"`
+	ClosedAt  *time.Time   `json:"closed_at,omitempty"`
+	Version   int64        `json:"version"`
+}
+
+type PartialPosting struct {
+	Title     *string       `json:"title"`
+	URL       *string       `json:"url"`
+	Location  *string       `json:"location"`
+	Remote    *RemotePolicy `json:"remote"`
+	SalaryMin *int64        `json:"salary_min"`
+	SalaryMax *int64        `json:"salary_max"`
+	PostedAt  *time.Time    `json:"posted_at"`
+	ClosedAt  *time.Time    `json:"closed_at"`
+}
+
+func validateSalary(v *validator.Validator, salaryMin *int64, salaryMax *int64) {
+	if salaryMin != nil {
+		v.Check(*salaryMin >= 0, "salary_min", "must not be negative")
+	}
+	if salaryMax != nil {
+		v.Check(*salaryMax >= 0, "salary_max", "must not be negative")
+	}
+	if salaryMin != nil && salaryMax != nil {
+		v.Check(*salaryMin <= *salaryMax, "salary_max", "must not be less than salary_min")
+	}
+}
+
+func validateDates(v *validator.Validator, postedAt *time.Time, closedAt *time.Time) {
+	if postedAt != nil && closedAt != nil {
+		v.Check(!closedAt.Before(*postedAt), "closed_at", "must not be before posted_at")
+	}
+}
+
+func (p *Posting) Validate(v *validator.Validator) {
+	v.Check(p.Title != "", "title", "must be provided")
+	v.Check(len(p.Title) <= 256, "title", "must not be more than 256 bytes")
+
+	if p.URL != "" {
+		v.Check(validator.IsURL(p.URL), "url", "must be a valid URL")
+	}
+
+	v.Check(len(p.Location) <= 256, "location", "must not be more than 256 bytes")
+
+	p.Remote.Validate(v)
+
+	validateSalary(v, p.SalaryMin, p.SalaryMax)
+	validateDates(v, p.PostedAt, p.ClosedAt)
+}
+
+func (pp *PartialPosting) Validate(v *validator.Validator) {
+	if pp.Title != nil {
+		v.Check(*pp.Title != "", "title", "must be provided")
+		v.Check(len(*pp.Title) <= 256, "title", "must not be more than 256 bytes")
+	}
+
+	if pp.URL != nil && *pp.URL != "" {
+		v.Check(validator.IsURL(*pp.URL), "url", "must be a valid URL")
+	}
+
+	if pp.Location != nil {
+		v.Check(len(*pp.Location) <= 256, "location", "must not be more than 256 bytes")
+	}
+
+	if pp.Remote != nil {
+		pp.Remote.Validate(v)
+	}
+
+	validateSalary(v, pp.SalaryMin, pp.SalaryMax)
+	validateDates(v, pp.PostedAt, pp.ClosedAt)
+}
+
+// Apply copies the fields that are set in the partial onto the posting. Checks that span two fields, like salary_min
+// against salary_max, can only be made against the result.
+func (pp *PartialPosting) Apply(p *Posting) {
+	if pp.Title != nil {
+		p.Title = *pp.Title
+	}
+	if pp.URL != nil {
+		p.URL = *pp.URL
+	}
+	if pp.Location != nil {
+		p.Location = *pp.Location
+	}
+	if pp.Remote != nil {
+		p.Remote = *pp.Remote
+	}
+	if pp.SalaryMin != nil {
+		p.SalaryMin = pp.SalaryMin
+	}
+	if pp.SalaryMax != nil {
+		p.SalaryMax = pp.SalaryMax
+	}
+	if pp.PostedAt != nil {
+		p.PostedAt = pp.PostedAt
+	}
+	if pp.ClosedAt != nil {
+		p.ClosedAt = pp.ClosedAt
+	}
+}
@@ -0,0 +1,81 @@
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dusktreader/the-hunt/internal/types"
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+func TestPartialPostingApply(t *testing.T) {
+	salary := func(s int64) *int64 {
+		return &s
+	}
+	day := func(d int) *time.Time {
+		t := time.Date(2025, time.March, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	cases := []struct {
+		name      string
+		partial   types.PartialPosting
+		wantValid bool
+	}{
+		{
+			name:      "raised salary_max",
+			partial:   types.PartialPosting{SalaryMax: salary(150)},
+			wantValid: true,
+		},
+		{
+			name:      "salary_min above the stored salary_max",
+			partial:   types.PartialPosting{SalaryMin: salary(150)},
+			wantValid: false,
+		},
+		{
+			name:      "salary_max below the stored salary_min",
+			partial:   types.PartialPosting{SalaryMax: salary(50)},
+			wantValid: false,
+		},
+		{
+			name:      "closed_at after the stored posted_at",
+			partial:   types.PartialPosting{ClosedAt: day(20)},
+			wantValid: true,
+		},
+		{
+			name:      "closed_at before the stored posted_at",
+			partial:   types.PartialPosting{ClosedAt: day(1)},
+			wantValid: false,
+		},
+		{
+			name:      "posted_at after the stored closed_at",
+			partial:   types.PartialPosting{PostedAt: day(31)},
+			wantValid: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := &types.Posting{
+				Title:     "Engineer",
+				Remote:    types.RemoteFull,
+				SalaryMin: salary(80),
+				SalaryMax: salary(120),
+				PostedAt:  day(10),
+				ClosedAt:  day(30),
+			}
+
+			v := validator.New()
+			c.partial.Validate(v)
+			if !v.Valid() {
+				t.Fatalf("Partial posting is invalid on its own: %v", v.Errors())
+			}
+
+			c.partial.Apply(p)
+			p.Validate(v)
+			if v.Valid() != c.wantValid {
+				t.Errorf("Valid() = %v; want %v (errors: %v)", v.Valid(), c.wantValid, v.Errors())
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"github.com/hashicorp/go-set/v3"
+
+	"github.com/dusktreader/the-hunt/internal/validator"
+)
+
+// Role is a named bundle of permission codes that can be assigned to users.
+type Role struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Permissions []PermCode `json:"permissions"`
+}
+
+// ValidateRoleNames checks that a list of role names submitted by a client is free of duplicates and only contains
+// roles that exist.
+func ValidateRoleNames(v *validator.Validator, names []string, known []string) {
+	v.Check(names != nil, "roles", "must be provided")
+	v.Check(validator.Unique(names), "roles", "must not contain duplicate items")
+	v.Check(set.From(known).Subset(set.From(names)), "roles", "must only include known roles")
+}
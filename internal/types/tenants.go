@@ -0,0 +1,22 @@
+package types
+
+// Tenant identifies whose hunt data a request may touch. Regular users only see their own rows (plus anything that
+// has been explicitly shared), while admin requests set All to reach across every tenant.
+type Tenant struct {
+	UserID int64
+	All    bool
+}
+
+func UserTenant(userID int64) Tenant {
+	return Tenant{UserID: userID}
+}
+
+// AdminTenant reaches across every tenant. Rows that an admin creates are still owned by the admin.
+func AdminTenant(userID int64) Tenant {
+	return Tenant{UserID: userID, All: true}
+}
+
+// Owns reports whether the tenant may modify a row owned by ownerID.
+func (t Tenant) Owns(ownerID int64) bool {
+	return t.All || t.UserID == ownerID
+}
@@ -12,16 +12,58 @@ type TokenScope string
 
 const ScopeActivation TokenScope = "activation"
 const ScopeAuthentication TokenScope = "authentication"
+const ScopePasswordReset TokenScope = "password-reset"
+const ScopeRefresh TokenScope = "refresh"
+const ScopeMFAPending TokenScope = "mfa_pending"
+
+// TokenMode selects how access tokens are issued. Opaque tokens are looked up in the database on every request. JWT
+// tokens are signed and carry everything needed to authenticate a request, including the user's permissions. Changing
+// a user's permissions or roles therefore ends their sessions in JWT mode.
+type TokenMode string
+
+const TokenModeOpaque TokenMode = "opaque"
+const TokenModeJWT TokenMode = "jwt"
 
 type PlainToken string
 
 type Token struct {
-	Plaintext PlainToken `json:"token"`
-	Hash      []byte     `json:"-"`
-	UserID    int64      `json:"-"`
-	ExpiresAt time.Time  `json:"expires_at"`
-	Scope     TokenScope `json:"-"`
-	IsAdmin   bool       `json:"-"`
+	ID         int64      `json:"-"`
+	Plaintext  PlainToken `json:"token"`
+	Hash       []byte     `json:"-"`
+	UserID     int64      `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Scope      TokenScope `json:"-"`
+	IP         string     `json:"-"`
+	UserAgent  string     `json:"-"`
+	Family     string     `json:"-"`
+	RotatedAt  *time.Time `json:"-"`
+	LastUsedAt *time.Time `json:"-"`
+}
+
+// Session describes an authentication token without exposing the token itself.
+type Session struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	Current    bool       `json:"current"`
+}
+
+// TokenInfo describes a token of any scope without exposing the token itself. It's what operators see when they
+// audit a user's tokens.
+type TokenInfo struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Scope      TokenScope `json:"scope"`
+	Family     string     `json:"family,omitzero"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RotatedAt  *time.Time `json:"rotated_at"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
 }
 
 func Hash(plaintext string) []byte {
@@ -29,7 +71,13 @@ func Hash(plaintext string) []byte {
 	return hash[:]
 }
 
-func GenerateToken(userID int64, ttl time.Duration, scope TokenScope, isAdmin bool) *Token {
+// NewTokenFamily creates an identifier shared by an access token, its refresh token, and every token that is later
+// issued by rotating that refresh token.
+func NewTokenFamily() string {
+	return rand.Text()
+}
+
+func GenerateToken(userID int64, ttl time.Duration, scope TokenScope) *Token {
 	plaintext := rand.Text()
 	return &Token{
 		Plaintext: PlainToken(plaintext),
@@ -37,7 +85,6 @@ func GenerateToken(userID int64, ttl time.Duration, scope TokenScope, isAdmin bo
 		UserID:    userID,
 		ExpiresAt: time.Now().Add(ttl),
 		Scope:     scope,
-		IsAdmin:   isAdmin,
 	}
 }
 
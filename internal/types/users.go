@@ -15,6 +15,7 @@ type User struct {
 	PlainPassword  PlainPW   `json:"-"`
 	HashedPassword HashPW    `json:"-"`
 	Activated      bool      `json:"activated"`
+	IsAdmin        bool      `json:"is_admin"`
 	Version        int64     `json:"version"`
 }
 
@@ -27,7 +28,9 @@ type PartialUser struct {
 
 var AnonymousUser = &User{}
 
-const AdminUserID = 0
+// SystemUserID stands in for the acting user when a change isn't made by anyone in the users table, such as during
+// bootstrapping.
+const SystemUserID = 0
 
 func (u *User) Validate(v *validator.Validator) {
 	v.Check(u.Name != "", "name", "must be provided")
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+create table postings (
+  id         bigserial                   primary key,
+  created_at timestamp(0) with time zone not null default now(),
+  updated_at timestamp(0) with time zone not null default now(),
+  company_id bigint                      not null references companies(id) on delete cascade,
+  title      text                        not null,
+  url        text                        not null default '',
+  location   text                        not null default '',
+  remote     text                        not null default 'onsite' check (remote in ('onsite', 'hybrid', 'remote')),
+  salary_min bigint,
+  salary_max bigint,
+  posted_at  timestamp(0) with time zone,
+  closed_at  timestamp(0) with time zone,
+  version    bigint                      not null default 1,
+  check (salary_min <= salary_max),
+  check (closed_at >= posted_at)
+);
+
+insert into permissions (code) values
+    ('postings:read'),
+    ('postings:write')
+;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from permissions where code in ('postings:read', 'postings:write');
+drop table postings;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+create table applications (
+  id         bigserial                   primary key,
+  created_at timestamp(0) with time zone not null default now(),
+  updated_at timestamp(0) with time zone not null default now(),
+  posting_id bigint                      unique not null references postings(id) on delete cascade,
+  status     text                        not null,
+  notes      text                        not null default '',
+  version    bigint                      not null default 1
+);
+
+create table application_transitions (
+  id             bigserial                   primary key,
+  created_at     timestamp(0) with time zone not null default now(),
+  application_id bigint                      not null references applications(id) on delete cascade,
+  from_status    text,
+  to_status      text                        not null,
+  note           text                        not null default ''
+);
+
+insert into permissions (code) values
+    ('applications:read'),
+    ('applications:write')
+;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from permissions where code in ('applications:read', 'applications:write');
+drop table application_transitions;
+drop table applications;
+-- +goose StatementEnd
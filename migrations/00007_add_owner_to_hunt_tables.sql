@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table companies
+    add column owner_id   bigint references users(id) on delete cascade,
+    add column visibility text   not null default 'private' check (visibility in ('private', 'public')),
+    drop constraint companies_name_key,
+    add constraint companies_owner_name_key unique nulls not distinct (owner_id, name);
+
+-- Companies created before tenancy have no owner, so keep them visible to everyone
+update companies set visibility = 'public' where owner_id is null;
+
+alter table postings
+    add column owner_id bigint references users(id) on delete cascade;
+
+alter table applications
+    add column owner_id bigint references users(id) on delete cascade;
+
+-- Postings and applications belong to whoever owns the company they hang off of
+update postings p
+    set owner_id = c.owner_id
+    from companies c
+    where c.id = p.company_id;
+
+update applications a
+    set owner_id = p.owner_id
+    from postings p
+    where p.id = a.posting_id;
+
+-- Any row that is still unowned (because its company has no owner) is only reachable by admins. Regular users can
+-- see an unowned company but not the postings and applications under it. An admin can hand those rows to a user by
+-- setting their owner_id.
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table applications
+    drop column owner_id;
+
+alter table postings
+    drop column owner_id;
+
+alter table companies
+    drop constraint companies_owner_name_key,
+    drop column visibility,
+    drop column owner_id,
+    add constraint companies_name_key unique (name);
+-- +goose StatementEnd
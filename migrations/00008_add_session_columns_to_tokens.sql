@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table tokens
+    add column id           bigserial                   unique,
+    add column created_at   timestamp(0) with time zone not null default now(),
+    add column last_used_at timestamp(0) with time zone,
+    add column ip           text                        not null default '',
+    add column user_agent   text                        not null default '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table tokens
+    drop column user_agent,
+    drop column ip,
+    drop column last_used_at,
+    drop column created_at,
+    drop column id;
+-- +goose StatementEnd
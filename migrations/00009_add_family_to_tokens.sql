@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table tokens
+    add column family     text,
+    add column rotated_at timestamp(0) with time zone;
+
+create index tokens_family_idx on tokens (family);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index tokens_family_idx;
+
+alter table tokens
+    drop column rotated_at,
+    drop column family;
+-- +goose StatementEnd
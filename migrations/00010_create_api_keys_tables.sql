@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+create table api_keys (
+    id           bigserial                   primary key,
+    created_at   timestamp(0) with time zone not null default now(),
+    user_id      bigint                      not null references users(id) on delete cascade,
+    name         text                        not null,
+    hash         bytea                       not null unique,
+    expires_at   timestamp(0) with time zone,
+    last_used_at timestamp(0) with time zone,
+
+    unique (user_id, name)
+);
+
+create table api_key_permissions (
+    api_key_id    bigint not null references api_keys(id) on delete cascade,
+    permission_id bigint not null references permissions(id) on delete cascade,
+
+    primary key (api_key_id, permission_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table api_key_permissions;
+drop table api_keys;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+create table permission_audit (
+    id         bigserial                   primary key,
+    created_at timestamp(0) with time zone not null default now(),
+    actor_id   bigint                      references users(id) on delete set null,
+    user_id    bigint                      not null references users(id) on delete cascade,
+    action     text                        not null check (action in ('grant', 'revoke')),
+    code       text                        not null
+);
+
+insert into permissions (code) values
+    ('permissions:admin')
+;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from permissions where code = 'permissions:admin';
+
+drop table permission_audit;
+-- +goose StatementEnd
@@ -0,0 +1,77 @@
+-- +goose Up
+-- +goose StatementBegin
+create table roles (
+    id   bigserial primary key,
+    name text      not null unique
+);
+
+create table role_permissions (
+    role_id       bigint not null references roles(id) on delete cascade,
+    permission_id bigint not null references permissions(id) on delete cascade,
+
+    primary key (role_id, permission_id)
+);
+
+create table user_roles (
+    user_id bigint not null references users(id) on delete cascade,
+    role_id bigint not null references roles(id) on delete cascade,
+
+    primary key (user_id, role_id)
+);
+
+insert into roles (name) values
+    ('viewer'),
+    ('member'),
+    ('admin')
+;
+
+insert into role_permissions (role_id, permission_id)
+select roles.id, permissions.id
+from roles, permissions
+where (roles.name = 'viewer' and permissions.code in ('companies:read', 'postings:read', 'applications:read'))
+or (
+    roles.name = 'member'
+    and permissions.code in (
+        'companies:read',
+        'companies:write',
+        'postings:read',
+        'postings:write',
+        'applications:read',
+        'applications:write'
+    )
+)
+or roles.name = 'admin';
+
+-- Users who registered before roles existed get the member role, which is what DEFAULT_ROLES gives new users unless
+-- it's changed.
+insert into user_roles (user_id, role_id)
+select users.id, roles.id
+from users, roles
+where roles.name = 'member';
+
+alter table permission_audit
+    drop constraint permission_audit_action_check,
+    alter column code drop not null,
+    add column role text,
+    add constraint permission_audit_action_check check (action in ('grant', 'revoke', 'assign', 'unassign'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from permission_audit where action in ('assign', 'unassign');
+
+alter table permission_audit
+    drop constraint permission_audit_action_check,
+    drop column role,
+    alter column code set not null,
+    add constraint permission_audit_action_check check (action in ('grant', 'revoke'));
+
+delete from user_roles
+using roles
+where user_roles.role_id = roles.id
+and roles.name = 'member';
+
+drop table user_roles;
+drop table role_permissions;
+drop table roles;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column is_admin bool not null default false;
+
+-- Tokens without a user were issued to the config-based admin login, which no longer exists.
+delete from tokens where user_id is null;
+
+alter table tokens
+    drop column is_admin,
+    alter column user_id set not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table tokens
+    alter column user_id drop not null,
+    add column is_admin boolean not null default false;
+
+alter table users
+    drop column is_admin;
+-- +goose StatementEnd
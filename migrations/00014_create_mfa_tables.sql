@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+create table user_mfa (
+    user_id    bigint                      primary key references users(id) on delete cascade,
+    created_at timestamp(0) with time zone not null default now(),
+    secret     text                        not null,
+    enabled    bool                        not null default false,
+    last_step  bigint                      not null default 0
+);
+
+create table mfa_recovery_codes (
+    id      bigserial primary key,
+    user_id bigint    not null references users(id) on delete cascade,
+    hash    bytea     not null unique
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table mfa_recovery_codes;
+drop table user_mfa;
+-- +goose StatementEnd
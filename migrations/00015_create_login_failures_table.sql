@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+create table login_failures (
+    kind           text                        not null check (kind in ('email', 'ip')),
+    subject        text                        not null,
+    failures       integer                     not null default 0,
+    last_failed_at timestamp(0) with time zone not null default now(),
+    locked_until   timestamp(0) with time zone,
+
+    primary key (kind, subject)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table login_failures;
+-- +goose StatementEnd
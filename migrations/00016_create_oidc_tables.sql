@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+create table oidc_logins (
+    state_hash    bytea                       primary key,
+    code_verifier text                        not null,
+    nonce         text                        not null,
+    expires_at    timestamp(0) with time zone not null
+);
+
+create table user_identities (
+    issuer     text                        not null,
+    subject    text                        not null,
+    user_id    bigint                      not null references users on delete cascade,
+    created_at timestamp(0) with time zone not null default now(),
+
+    primary key (issuer, subject)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table user_identities;
+drop table oidc_logins;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+create table token_denylist (
+    family     text                        primary key,
+    expires_at timestamp(0) with time zone not null
+);
+
+-- Signed access tokens are checked without looking them up, so deleting one doesn't revoke it. Whenever an
+-- authentication token is deleted, for any reason, its family is denied until the token would have expired.
+create function deny_token_family() returns trigger as $$
+begin
+    insert into token_denylist (family, expires_at)
+    values (old.family, old.expires_at)
+    on conflict (family) do update
+    set expires_at = greatest(token_denylist.expires_at, excluded.expires_at);
+    return old;
+end;
+$$ language plpgsql;
+
+create trigger tokens_deny_family
+after delete on tokens
+for each row
+when (old.scope = 'authentication' and old.family is not null)
+execute function deny_token_family();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger tokens_deny_family on tokens;
+drop function deny_token_family;
+drop table token_denylist;
+-- +goose StatementEnd
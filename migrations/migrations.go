@@ -0,0 +1,7 @@
+// Package migrations embeds the goose migrations so that they can be applied without the SQL files on disk.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS